@@ -43,4 +43,38 @@ type Accumulator interface {
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking upgrades to a TrackingAccumulator with space for maxTracked
+	// metric groups to be pending delivery at once.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID uniquely identifies a tracked metric group.
+type TrackingID uint64
+
+// DeliveryInfo provides the results of a delivered metric group.
+type DeliveryInfo interface {
+	// ID is the TrackingID returned when the group was added.
+	ID() TrackingID
+
+	// Delivered returns true if the metric group was written successfully
+	// by every output, or dropped on purpose (ie, by a filter).
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator that provides a signal when the
+// metrics it was given have been fully processed by all outputs. This is used
+// by queue consumers to only acknowledge messages once they are written.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds a group of metrics that are tracked as a
+	// unit, and returns the TrackingID that will be reported on the
+	// Delivered channel once every metric of the group has been processed.
+	// Callers must not have more than maxTracked groups pending delivery.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns a channel that receives the results of each metric
+	// group once it has been processed.
+	Delivered() <-chan DeliveryInfo
 }
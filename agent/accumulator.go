@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -23,6 +24,12 @@ type MetricMaker interface {
 	) telegraf.Metric
 }
 
+// errorRecorder is implemented by the plugins which keep track of their last
+// error.
+type errorRecorder interface {
+	SetError(err error)
+}
+
 func NewAccumulator(
 	maker MetricMaker,
 	metrics chan telegraf.Metric,
@@ -105,6 +112,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.SetError(err)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
 	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
 }
@@ -130,6 +140,53 @@ func (ac *accumulator) SetPrecision(precision, interval time.Duration) {
 	}
 }
 
+// WithTracking returns a TrackingAccumulator that sends metrics to the same
+// channel as ac.
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+// AddTrackingMetricGroup runs each metric of the group through the
+// MetricMaker, and adds the resulting metrics as a tracked group.
+func (a *trackingAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	made := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		t := m.Time()
+		if t.IsZero() {
+			t = time.Now()
+		}
+		mm := a.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(),
+			t.Round(a.precision))
+		if mm != nil {
+			made = append(made, mm)
+		}
+	}
+
+	tracked, id := metric.NewTrackingMetricGroup(made, a.onDelivery)
+	for _, m := range tracked {
+		a.metrics <- m
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
+}
+
 func (ac accumulator) getTime(t []time.Time) time.Time {
 	var timestamp time.Time
 	if len(t) > 0 {
@@ -338,3 +338,44 @@ func (tm *TestMetricMaker) MakeMetric(
 	}
 	return nil
 }
+
+func TestAddTrackingMetricGroup(t *testing.T) {
+	now := time.Now()
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(1)
+
+	m1, err := metric.New("acctest",
+		map[string]string{},
+		map[string]interface{}{"value": float64(101)},
+		now,
+	)
+	require.NoError(t, err)
+	m2, err := metric.New("acctest",
+		map[string]string{"acc": "test"},
+		map[string]interface{}{"value": float64(102)},
+		now,
+	)
+	require.NoError(t, err)
+
+	id := a.AddTrackingMetricGroup([]telegraf.Metric{m1, m2})
+
+	testm := <-metrics
+	assert.Equal(t,
+		fmt.Sprintf("acctest value=101 %d\n", now.UnixNano()),
+		testm.String())
+	testm.Accept()
+
+	select {
+	case <-a.Delivered():
+		assert.Fail(t, "group delivered before all metrics were accepted")
+	default:
+	}
+
+	testm = <-metrics
+	testm.Accept()
+
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,12 +13,48 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
-	"github.com/influxdata/telegraf/selfstat"
 )
 
+// ErrRestartRequired is returned by Reload when the new configuration
+// changes the [agent] or [global_tags] settings, which are only applied by
+// restarting the agent.
+var ErrRestartRequired = errors.New("agent settings changed, restart required")
+
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// mu guards Config, which is replaced by Reload while the agent runs.
+	mu sync.RWMutex
+
+	// runMu serializes Reload with the start and shutdown of Run.
+	runMu sync.Mutex
+	// running is the state of Run, nil when the agent is not running.
+	running *runState
+}
+
+// runState holds the channels and plugin goroutines of a running agent.
+type runState struct {
+	metricC     chan telegraf.Metric
+	aggC        chan telegraf.Metric
+	inputs      map[*models.RunningInput]*runner
+	aggregators map[*models.RunningAggregator]*runner
+	outputs     map[*models.RunningOutput]*runner
+}
+
+// runner is the goroutine of a single input, aggregator or output.
+type runner struct {
+	stop chan struct{}
+	done chan struct{}
+	// service is set for started service inputs.
+	service telegraf.ServiceInput
+}
+
+func newRunner() *runner {
+	return &runner{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
 }
 
 // NewAgent returns an Agent struct based off the given Config
@@ -26,63 +63,184 @@ func NewAgent(config *config.Config) (*Agent, error) {
 		Config: config,
 	}
 
-	if !a.Config.Agent.OmitHostname {
-		if a.Config.Agent.Hostname == "" {
-			hostname, err := os.Hostname()
-			if err != nil {
-				return nil, err
-			}
+	if err := setHostTag(config); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// setHostTag sets the hostname of c and adds it to the global tags, unless
+// omit_hostname is set.
+func setHostTag(c *config.Config) error {
+	if c.Agent.OmitHostname {
+		return nil
+	}
 
-			a.Config.Agent.Hostname = hostname
+	if c.Agent.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
 		}
 
-		config.Tags["host"] = a.Config.Agent.Hostname
+		c.Agent.Hostname = hostname
 	}
 
-	return a, nil
+	c.Tags["host"] = c.Agent.Hostname
+	return nil
+}
+
+// config returns the current configuration of the agent.
+func (a *Agent) config() *config.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config
 }
 
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
-	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+	for _, o := range a.config().Outputs {
+		if err := connectOutput(o); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+func connectOutput(o *models.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.Name, err.Error())
+			return err
+		}
+	}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
-		err := o.Output.Connect()
+	log.Printf("D! Attempting connection to output: %s\n", o.Name)
+	err := o.Output.Connect()
+	if err != nil {
+		log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
+			"error was '%s' \n", o.Name, err)
+		time.Sleep(15 * time.Second)
+		err = o.Output.Connect()
 		if err != nil {
-			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
+			return err
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	}
+	log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	return nil
 }
 
 // Close closes the connection to all configured outputs
 func (a *Agent) Close() error {
 	var err error
-	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+	for _, o := range a.config().Outputs {
+		err = closeOutput(o)
 	}
 	return err
 }
 
+func closeOutput(o *models.RunningOutput) error {
+	err := o.Output.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
+	}
+	if berr := o.Close(); berr != nil {
+		log.Printf("E! Error closing buffer of output [%s]: %s\n",
+			o.Name, berr)
+	}
+	return err
+}
+
+// Reload applies a new configuration to the running agent. Only the plugins
+// that were added, removed or changed are stopped and started; the others
+// keep running along with the metrics they have buffered. If an error is
+// returned, the running configuration is kept and the outputs of c are
+// closed, unless the error is ErrRestartRequired.
+func (a *Agent) Reload(c *config.Config) error {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	rs := a.running
+	if rs == nil {
+		c.Close()
+		return errors.New("agent is not running")
+	}
+
+	if err := setHostTag(c); err != nil {
+		c.Close()
+		return err
+	}
+	if c.AgentChanged(a.Config) {
+		return ErrRestartRequired
+	}
+	diff := c.Reconcile(a.Config)
+
+	// connect the new outputs first, so that the running configuration can
+	// be kept if one of them fails.
+	for i, o := range diff.AddedOutputs {
+		if err := connectOutput(o); err != nil {
+			for _, o := range diff.AddedOutputs[:i] {
+				closeOutput(o)
+			}
+			for _, o := range diff.AddedOutputs[i:] {
+				o.Close()
+			}
+			for _, o := range diff.UnusedOutputs {
+				o.Close()
+			}
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	a.Config = c
+	a.mu.Unlock()
+
+	for _, input := range diff.RemovedInputs {
+		a.stopInput(rs, input)
+	}
+	for _, agg := range diff.RemovedAggregators {
+		a.stopAggregator(rs, agg)
+	}
+
+	now := time.Now()
+	for _, agg := range diff.AddedAggregators {
+		a.startAggregator(rs, agg, now)
+	}
+	for _, input := range diff.AddedInputs {
+		if err := a.startService(rs, input); err != nil {
+			continue
+		}
+		a.startGatherer(rs, input)
+	}
+
+	// the removed outputs are stopped first: a changed output shares its
+	// disk buffer with the instance it replaces, which must be done reading
+	// from it.
+	for _, o := range diff.RemovedOutputs {
+		// the output is flushed once more as its loop stops
+		a.stopOutput(rs, o)
+		closeOutput(o)
+	}
+	for _, o := range diff.AddedOutputs {
+		a.startOutput(rs, o)
+	}
+	// the new instances of unchanged outputs are not used
+	for _, o := range diff.UnusedOutputs {
+		o.Close()
+	}
+
+	log.Printf("I! Reloaded config: %d inputs, %d outputs and %d aggregators "+
+		"stopped; %d inputs, %d outputs and %d aggregators started\n",
+		len(diff.RemovedInputs), len(diff.RemovedOutputs),
+		len(diff.RemovedAggregators), len(diff.AddedInputs),
+		len(diff.AddedOutputs), len(diff.AddedAggregators))
+	return nil
+}
+
 func panicRecover(input *models.RunningInput) {
 	if err := recover(); err != nil {
 		trace := make([]byte, 2048)
@@ -105,26 +263,19 @@ func (a *Agent) gatherer(
 ) {
 	defer panicRecover(input)
 
-	GatherTime := selfstat.RegisterTiming("gather",
-		"gather_time_ns",
-		map[string]string{"input": input.Config.Name},
-	)
-
 	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
-		a.Config.Agent.Interval.Duration)
+	acc.SetPrecision(a.config().Agent.Precision.Duration,
+		a.config().Agent.Interval.Duration)
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		internal.RandomSleep(a.config().Agent.CollectionJitter.Duration, shutdown)
 
 		start := time.Now()
 		gatherWithTimeout(shutdown, input, acc, interval)
-		elapsed := time.Since(start)
-
-		GatherTime.Incr(elapsed.Nanoseconds())
+		input.GatherDone(start)
 
 		select {
 		case <-shutdown:
@@ -227,31 +378,84 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// flush writes a list of metrics to all configured outputs
-func (a *Agent) flush() {
-	var wg sync.WaitGroup
+// flushLoop writes the metrics of an output on its flush interval, and as
+// soon as a full batch is ready. Each output has its own loop, so that a slow
+// output only holds up its own metrics. The output is flushed a last time
+// when shutdown is closed.
+func (a *Agent) flushLoop(
+	shutdown chan struct{},
+	output *models.RunningOutput,
+	interval time.Duration,
+	jitter time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
-			}
-		}(o)
+	for {
+		select {
+		case <-shutdown:
+			flush(output, interval)
+			return
+		case <-ticker.C:
+			internal.RandomSleep(jitter, shutdown)
+			flush(output, interval)
+		case <-output.BatchReady:
+			flush(output, interval)
+		}
 	}
+}
 
-	wg.Wait()
+// flush writes the buffered metrics of output.
+func flush(output *models.RunningOutput, interval time.Duration) {
+	start := time.Now()
+	err := output.Write()
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.Name, err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > interval {
+		log.Printf("W! Output [%s] took %s to flush, longer than its flush "+
+			"interval of %s\n", output.Name, elapsed, interval)
+	}
 }
 
-// flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
-	// Inelegant, but this sleep is to allow the Gather threads to run, so that
-	// the flusher will flush after metrics are collected.
-	time.Sleep(time.Millisecond * 300)
+// process runs the metrics through all configured processors.
+func (a *Agent) process(metrics []telegraf.Metric) []telegraf.Metric {
+	for _, processor := range a.config().Processors {
+		metrics = processor.Apply(metrics...)
+	}
+	return metrics
+}
+
+// dispatch passes a processed metric on to the aggregators and outputs.
+func (a *Agent) dispatch(m telegraf.Metric) {
+	c := a.config()
+
+	// if dropOriginal is set to true, then we will only send this
+	// metric to the aggregators, not the outputs.
+	var dropOriginal bool
+	if !m.IsAggregate() {
+		for _, agg := range c.Aggregators {
+			if ok := agg.Add(m.Copy()); ok {
+				dropOriginal = true
+			}
+		}
+	}
+	if dropOriginal || len(c.Outputs) == 0 {
+		m.Drop()
+		return
+	}
+	for i, o := range c.Outputs {
+		if i == len(c.Outputs)-1 {
+			o.AddMetric(m)
+		} else {
+			o.AddMetric(m.Copy())
+		}
+	}
+}
 
+// flusher passes the metrics of the inputs and aggregators on to the outputs.
+func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
 	// create an output metric channel and a gorouting that continuously passes
 	// each metric onto the output plugins & aggregators.
 	outMetricC := make(chan telegraf.Metric, 100)
@@ -268,25 +472,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case m := <-outMetricC:
-				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
-				var dropOriginal bool
-				if !m.IsAggregate() {
-					for _, agg := range a.Config.Aggregators {
-						if ok := agg.Add(m.Copy()); ok {
-							dropOriginal = true
-						}
-					}
-				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
-					}
-				}
+				a.dispatch(m)
 			}
 		}
 	}()
@@ -303,10 +489,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case metric := <-aggC:
-				metrics := []telegraf.Metric{metric}
-				for _, processor := range a.Config.Processors {
-					metrics = processor.Apply(metrics...)
-				}
+				metrics := a.process([]telegraf.Metric{metric})
 				for _, m := range metrics {
 					outMetricC <- m
 				}
@@ -314,36 +497,18 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
 	for {
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+			// wait for outMetricC to get flushed before the outputs are
+			// flushed a last time
 			wg.Wait()
-			a.flush()
 			return nil
-		case <-ticker.C:
-			go func() {
-				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
-				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
-				}
-			}()
 		case metric := <-metricC:
 			// NOTE potential bottleneck here as we put each metric through the
 			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
-			}
+			mS := a.process([]telegraf.Metric{metric})
 			for _, m := range mS {
 				outMetricC <- m
 			}
@@ -351,6 +516,122 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 	}
 }
 
+// startService starts input if it is a service input.
+func (a *Agent) startService(rs *runState, input *models.RunningInput) error {
+	r := newRunner()
+	rs.inputs[input] = r
+
+	input.SetDefaultTags(a.Config.Tags)
+	switch p := input.Input.(type) {
+	case telegraf.ServiceInput:
+		acc := NewAccumulator(input, rs.metricC)
+		// Service input plugins should set their own precision of their
+		// metrics.
+		acc.SetPrecision(time.Nanosecond, 0)
+		if err := p.Start(acc); err != nil {
+			log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+				input.Name(), err.Error())
+			delete(rs.inputs, input)
+			return err
+		}
+		r.service = p
+	}
+	return nil
+}
+
+// startGatherer starts gathering from input on its interval.
+func (a *Agent) startGatherer(rs *runState, input *models.RunningInput) {
+	r := rs.inputs[input]
+
+	interval := a.Config.Agent.Interval.Duration
+	// overwrite global interval if this plugin has it's own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+	go func() {
+		defer close(r.done)
+		a.gatherer(r.stop, input, interval, rs.metricC)
+	}()
+}
+
+// stopInput stops gathering from input, and stops it if it is a service
+// input.
+func (a *Agent) stopInput(rs *runState, input *models.RunningInput) {
+	r, ok := rs.inputs[input]
+	if !ok {
+		return
+	}
+	delete(rs.inputs, input)
+
+	close(r.stop)
+	<-r.done
+	if r.service != nil {
+		r.service.Stop()
+	}
+}
+
+func (a *Agent) startAggregator(rs *runState, agg *models.RunningAggregator, now time.Time) {
+	r := newRunner()
+	rs.aggregators[agg] = r
+
+	go func() {
+		defer close(r.done)
+		acc := NewAccumulator(agg, rs.aggC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		agg.Run(acc, now, r.stop)
+	}()
+}
+
+func (a *Agent) stopAggregator(rs *runState, agg *models.RunningAggregator) {
+	r, ok := rs.aggregators[agg]
+	if !ok {
+		return
+	}
+	delete(rs.aggregators, agg)
+
+	close(r.stop)
+	<-r.done
+}
+
+// startOutput starts the flush loop of output.
+func (a *Agent) startOutput(rs *runState, output *models.RunningOutput) {
+	r := newRunner()
+	rs.outputs[output] = r
+
+	interval := a.Config.Agent.FlushInterval.Duration
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+	jitter := a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+	go func() {
+		defer close(r.done)
+		// Inelegant, but this sleep is to allow the Gather threads to run,
+		// so that the output is flushed after metrics are collected.
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.stop:
+		}
+		a.flushLoop(r.stop, output, interval, jitter)
+	}()
+}
+
+// stopOutput stops the flush loop of output, once it has been flushed a last
+// time.
+func (a *Agent) stopOutput(rs *runState, output *models.RunningOutput) {
+	r, ok := rs.outputs[output]
+	if !ok {
+		return
+	}
+	delete(rs.outputs, output)
+
+	close(r.stop)
+	<-r.done
+}
+
 // Run runs the agent daemon, gathering every Interval
 func (a *Agent) Run(shutdown chan struct{}) error {
 	var wg sync.WaitGroup
@@ -360,27 +641,37 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
-	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
-	aggC := make(chan telegraf.Metric, 100)
+	rs := &runState{
+		// channel shared between all input threads for accumulating metrics
+		metricC:     make(chan telegraf.Metric, 100),
+		aggC:        make(chan telegraf.Metric, 100),
+		inputs:      make(map[*models.RunningInput]*runner),
+		aggregators: make(map[*models.RunningAggregator]*runner),
+		outputs:     make(map[*models.RunningOutput]*runner),
+	}
+
+	if addr := a.Config.Agent.HTTPAPIAddress; addr != "" {
+		srv, err := a.startAPI(addr)
+		if err != nil {
+			return fmt.Errorf("could not start the agent API: %s", err)
+		}
+		defer srv.Close()
+	}
 
 	now := time.Now()
 
+	a.runMu.Lock()
+
 	// Start all ServicePlugins
 	for _, input := range a.Config.Inputs {
-		input.SetDefaultTags(a.Config.Tags)
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
+		if err := a.startService(rs, input); err != nil {
+			for _, r := range rs.inputs {
+				if r.service != nil {
+					r.service.Stop()
+				}
 			}
-			defer p.Stop()
+			a.runMu.Unlock()
+			return err
 		}
 	}
 
@@ -390,40 +681,65 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
 	}
 
+	for _, output := range a.Config.Outputs {
+		a.startOutput(rs, output)
+	}
+
+	// the flusher is stopped once the inputs and aggregators are, so that
+	// the aggregates pushed by the aggregators on shutdown are flushed.
+	flusherStop := make(chan struct{})
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.flusher(shutdown, metricC, aggC); err != nil {
+		if err := a.flusher(flusherStop, rs.metricC, rs.aggC); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
 	}()
 
-	wg.Add(len(a.Config.Aggregators))
 	for _, aggregator := range a.Config.Aggregators {
-		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
-			acc := NewAccumulator(agg, aggC)
-			acc.SetPrecision(a.Config.Agent.Precision.Duration,
-				a.Config.Agent.Interval.Duration)
-			agg.Run(acc, now, shutdown)
-		}(aggregator)
+		a.startAggregator(rs, aggregator, now)
 	}
 
-	wg.Add(len(a.Config.Inputs))
 	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		// overwrite global interval if this plugin has it's own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
-		go func(in *models.RunningInput, interv time.Duration) {
-			defer wg.Done()
-			a.gatherer(shutdown, in, interv, metricC)
-		}(input, interval)
+		a.startGatherer(rs, input)
 	}
 
+	a.running = rs
+	a.runMu.Unlock()
+
+	<-shutdown
+
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	a.running = nil
+
+	for _, r := range rs.inputs {
+		close(r.stop)
+	}
+	for _, r := range rs.aggregators {
+		close(r.stop)
+	}
+	for _, r := range rs.inputs {
+		<-r.done
+	}
+	for _, r := range rs.aggregators {
+		<-r.done
+	}
+	close(flusherStop)
 	wg.Wait()
+	for _, r := range rs.outputs {
+		close(r.stop)
+	}
+	for _, r := range rs.outputs {
+		<-r.done
+	}
 	a.Close()
+
+	for _, r := range rs.inputs {
+		if r.service != nil {
+			r.service.Stop()
+		}
+	}
 	return nil
 }
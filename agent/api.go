@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+type healthResponse struct {
+	Status         string          `json:"status"`
+	FailingOutputs []failingOutput `json:"failing_outputs,omitempty"`
+}
+
+type failingOutput struct {
+	Name         string    `json:"name"`
+	FailingSince time.Time `json:"failing_since"`
+	LastError    string    `json:"last_error"`
+}
+
+type statusResponse struct {
+	Inputs      []inputStatus      `json:"inputs"`
+	Outputs     []outputStatus     `json:"outputs"`
+	Aggregators []aggregatorStatus `json:"aggregators"`
+}
+
+type inputStatus struct {
+	Name                 string     `json:"name"`
+	MetricsGathered      int64      `json:"metrics_gathered"`
+	LastGather           *time.Time `json:"last_gather,omitempty"`
+	LastGatherDurationNs int64      `json:"last_gather_duration_ns"`
+	LastError            string     `json:"last_error,omitempty"`
+	LastErrorTime        *time.Time `json:"last_error_time,omitempty"`
+}
+
+type outputStatus struct {
+	Name                string     `json:"name"`
+	MetricsWritten      int64      `json:"metrics_written"`
+	BufferSize          int        `json:"buffer_size"`
+	BufferLimit         int        `json:"buffer_limit"`
+	LastWrite           *time.Time `json:"last_write,omitempty"`
+	LastWriteDurationNs int64      `json:"last_write_duration_ns"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	FailingSince        *time.Time `json:"failing_since,omitempty"`
+}
+
+type aggregatorStatus struct {
+	Name                   string     `json:"name"`
+	PeriodStart            *time.Time `json:"period_start,omitempty"`
+	PeriodEnd              *time.Time `json:"period_end,omitempty"`
+	MetricsDroppedLate     int64      `json:"metrics_dropped_late"`
+	MetricsDroppedEarly    int64      `json:"metrics_dropped_early"`
+	MetricsDroppedOverflow int64      `json:"metrics_dropped_overflow"`
+}
+
+// startAPI starts serving the agent API on addr. The returned server must be
+// closed once the agent stops.
+func (a *Agent) startAPI(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: a.apiHandler()}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Agent API stopped: %s", err)
+		}
+	}()
+	log.Printf("I! Agent API listening on %s", ln.Addr())
+	return srv, nil
+}
+
+func (a *Agent) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.serveHealth)
+	mux.HandleFunc("/status", a.serveStatus)
+	return mux
+}
+
+// serveHealth reports the agent as failing when an output has not been able
+// to write for longer than the configured threshold.
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	c := a.config()
+	threshold := c.Agent.HealthOutputFailureThreshold.Duration
+
+	resp := healthResponse{Status: "ok"}
+	for _, o := range c.Outputs {
+		status := o.Status()
+		if status.FailingSince.IsZero() || time.Since(status.FailingSince) < threshold {
+			continue
+		}
+		resp.FailingOutputs = append(resp.FailingOutputs, failingOutput{
+			Name:         "outputs." + o.Name,
+			FailingSince: status.FailingSince,
+			LastError:    status.LastError,
+		})
+	}
+
+	code := http.StatusOK
+	if len(resp.FailingOutputs) > 0 {
+		resp.Status = "failing"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+func (a *Agent) serveStatus(w http.ResponseWriter, r *http.Request) {
+	c := a.config()
+
+	resp := statusResponse{
+		Inputs:      make([]inputStatus, 0, len(c.Inputs)),
+		Outputs:     make([]outputStatus, 0, len(c.Outputs)),
+		Aggregators: make([]aggregatorStatus, 0, len(c.Aggregators)),
+	}
+	for _, i := range c.Inputs {
+		resp.Inputs = append(resp.Inputs, newInputStatus(i))
+	}
+	for _, o := range c.Outputs {
+		resp.Outputs = append(resp.Outputs, newOutputStatus(o))
+	}
+	for _, agg := range c.Aggregators {
+		start, end := agg.Period()
+		resp.Aggregators = append(resp.Aggregators, aggregatorStatus{
+			Name:                   agg.Name(),
+			PeriodStart:            optionalTime(start),
+			PeriodEnd:              optionalTime(end),
+			MetricsDroppedLate:     agg.MetricsDroppedLate.Get(),
+			MetricsDroppedEarly:    agg.MetricsDroppedEarly.Get(),
+			MetricsDroppedOverflow: agg.MetricsDroppedOverflow.Get(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func newInputStatus(i *models.RunningInput) inputStatus {
+	status := i.Status()
+	return inputStatus{
+		Name:                 i.Name(),
+		MetricsGathered:      i.MetricsGathered.Get(),
+		LastGather:           optionalTime(status.LastGather),
+		LastGatherDurationNs: status.LastGatherDuration.Nanoseconds(),
+		LastError:            status.LastError,
+		LastErrorTime:        optionalTime(status.LastErrorTime),
+	}
+}
+
+func newOutputStatus(o *models.RunningOutput) outputStatus {
+	status := o.Status()
+	return outputStatus{
+		Name:                "outputs." + o.Name,
+		MetricsWritten:      o.MetricsWritten.Get(),
+		BufferSize:          status.BufferSize,
+		BufferLimit:         status.BufferLimit,
+		LastWrite:           optionalTime(status.LastWrite),
+		LastWriteDurationNs: status.LastWriteDuration.Nanoseconds(),
+		LastError:           status.LastError,
+		LastErrorTime:       optionalTime(status.LastErrorTime),
+		FailingSince:        optionalTime(status.FailingSince),
+	}
+}
+
+// optionalTime returns nil for the zero time, so that it is omitted.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! Agent API could not write response: %s", err)
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTestInput struct{}
+
+func (i *apiTestInput) SampleConfig() string                  { return "" }
+func (i *apiTestInput) Description() string                   { return "" }
+func (i *apiTestInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+type apiTestOutput struct {
+	err error
+}
+
+func (o *apiTestOutput) Connect() error                        { return nil }
+func (o *apiTestOutput) Close() error                          { return nil }
+func (o *apiTestOutput) SampleConfig() string                  { return "" }
+func (o *apiTestOutput) Description() string                   { return "" }
+func (o *apiTestOutput) Write(metrics []telegraf.Metric) error { return o.err }
+
+func newAPITestAgent(t *testing.T) (*Agent, *models.RunningInput, *models.RunningOutput, *apiTestOutput) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+
+	input := models.NewRunningInput(&apiTestInput{}, &models.InputConfig{Name: "apitest"})
+	c.Inputs = append(c.Inputs, input)
+
+	output := &apiTestOutput{}
+	ro := models.NewRunningOutput("apitest", output, &models.OutputConfig{}, 0, 0)
+	c.Outputs = append(c.Outputs, ro)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	return a, input, ro, output
+}
+
+func get(t *testing.T, a *Agent, path string, v interface{}) int {
+	w := httptest.NewRecorder()
+	a.apiHandler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	return w.Code
+}
+
+func TestAPIHealth(t *testing.T) {
+	a, _, ro, output := newAPITestAgent(t)
+	a.Config.Agent.HealthOutputFailureThreshold.Duration = time.Hour
+
+	var resp healthResponse
+	assert.Equal(t, http.StatusOK, get(t, a, "/health", &resp))
+	assert.Equal(t, "ok", resp.Status)
+
+	// failing, but not for long enough
+	output.err = errors.New("connection refused")
+	ro.AddMetric(testutil.TestMetric(1))
+	require.Error(t, ro.Write())
+	assert.Equal(t, http.StatusOK, get(t, a, "/health", &resp))
+
+	a.Config.Agent.HealthOutputFailureThreshold.Duration = 0
+	resp = healthResponse{}
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, a, "/health", &resp))
+	assert.Equal(t, "failing", resp.Status)
+	require.Len(t, resp.FailingOutputs, 1)
+	assert.Equal(t, "outputs.apitest", resp.FailingOutputs[0].Name)
+	assert.Equal(t, "connection refused", resp.FailingOutputs[0].LastError)
+
+	// a successful write makes the agent healthy again
+	output.err = nil
+	require.NoError(t, ro.Write())
+	resp = healthResponse{}
+	assert.Equal(t, http.StatusOK, get(t, a, "/health", &resp))
+	assert.Empty(t, resp.FailingOutputs)
+}
+
+func TestAPIStatus(t *testing.T) {
+	a, input, ro, output := newAPITestAgent(t)
+
+	var resp statusResponse
+	assert.Equal(t, http.StatusOK, get(t, a, "/status", &resp))
+	require.Len(t, resp.Inputs, 1)
+	assert.Equal(t, "inputs.apitest", resp.Inputs[0].Name)
+	assert.Nil(t, resp.Inputs[0].LastGather)
+	require.Len(t, resp.Outputs, 1)
+	assert.Nil(t, resp.Outputs[0].LastWrite)
+	assert.Len(t, resp.Aggregators, 0)
+
+	start := time.Now()
+	input.GatherDone(start)
+	input.SetError(errors.New("gather failed"))
+
+	output.err = errors.New("write failed")
+	ro.AddMetric(testutil.TestMetric(1))
+	ro.AddMetric(testutil.TestMetric(2))
+	require.Error(t, ro.Write())
+
+	resp = statusResponse{}
+	assert.Equal(t, http.StatusOK, get(t, a, "/status", &resp))
+
+	require.Len(t, resp.Inputs, 1)
+	require.NotNil(t, resp.Inputs[0].LastGather)
+	assert.True(t, start.Equal(*resp.Inputs[0].LastGather))
+	assert.Equal(t, "gather failed", resp.Inputs[0].LastError)
+	assert.NotNil(t, resp.Inputs[0].LastErrorTime)
+
+	require.Len(t, resp.Outputs, 1)
+	out := resp.Outputs[0]
+	assert.Equal(t, "outputs.apitest", out.Name)
+	assert.Equal(t, 2, out.BufferSize)
+	assert.Equal(t, models.DEFAULT_METRIC_BUFFER_LIMIT, out.BufferLimit)
+	assert.NotNil(t, out.LastWrite)
+	assert.Equal(t, "write failed", out.LastError)
+	assert.NotNil(t, out.FailingSince)
+}
+
+func TestAPIAccumulatorRecordsErrors(t *testing.T) {
+	input := models.NewRunningInput(&apiTestInput{}, &models.InputConfig{Name: "apitest"})
+	acc := NewAccumulator(input, make(chan telegraf.Metric, 1))
+
+	acc.AddError(errors.New("oops"))
+	assert.Equal(t, "oops", input.Status().LastError)
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flushTestOutput struct {
+	// block, when not nil, makes Write wait until it is closed
+	block chan struct{}
+
+	mu      sync.Mutex
+	metrics []telegraf.Metric
+}
+
+func (o *flushTestOutput) Connect() error       { return nil }
+func (o *flushTestOutput) Close() error         { return nil }
+func (o *flushTestOutput) SampleConfig() string { return "" }
+func (o *flushTestOutput) Description() string  { return "" }
+
+func (o *flushTestOutput) Write(metrics []telegraf.Metric) error {
+	if o.block != nil {
+		<-o.block
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func (o *flushTestOutput) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.metrics)
+}
+
+func TestFlushSlowOutput(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.FlushInterval.Duration = time.Hour
+
+	slow := &flushTestOutput{block: make(chan struct{})}
+	slowRO := models.NewRunningOutput("slow", slow, &models.OutputConfig{}, 2, 100)
+	fast := &flushTestOutput{}
+	fastRO := models.NewRunningOutput("fast", fast,
+		&models.OutputConfig{FlushInterval: 10 * time.Millisecond}, 100, 100)
+	c.Outputs = append(c.Outputs, slowRO, fastRO)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	rs := &runState{outputs: make(map[*models.RunningOutput]*runner)}
+	a.startOutput(rs, slowRO)
+	a.startOutput(rs, fastRO)
+
+	// the slow output gets a full batch, and blocks writing it
+	for i := 0; i < 4; i++ {
+		a.dispatch(testutil.TestMetric(i))
+	}
+	assert.True(t, waitFor(func() bool { return fast.Len() == 4 }),
+		"metrics not written to the fast output")
+
+	// the fast output is flushed on its own interval while the slow one is
+	// still writing
+	a.dispatch(testutil.TestMetric(4))
+	assert.True(t, waitFor(func() bool { return fast.Len() == 5 }),
+		"metrics not written to the fast output")
+	assert.Equal(t, 0, slow.Len())
+
+	// the slow output writes all its metrics once unblocked, the last ones
+	// as its loop stops
+	close(slow.block)
+	a.stopOutput(rs, slowRO)
+	a.stopOutput(rs, fastRO)
+	assert.Equal(t, 5, slow.Len())
+	assert.Equal(t, 5, fast.Len())
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -8,9 +9,11 @@ import (
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
@@ -22,6 +25,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
 	"github.com/kardianos/service"
+	"gopkg.in/fsnotify.v1"
 )
 
 var fDebug = flag.Bool("debug", false,
@@ -34,6 +38,8 @@ var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the configuration when the config file or directory changes")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -86,6 +92,7 @@ The commands & flags are:
   --config <file>     configuration file to load
   --test              gather metrics once, print them to stdout, and exit
   --config-directory  directory containing additional *.conf files
+  --watch-config      reload the configuration when the config file or directory changes
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -125,40 +132,22 @@ func reloadLoop(
 ) {
 	reload := make(chan bool, 1)
 	reload <- true
+	// restartConfig is a reloaded config that could only be applied by
+	// restarting the agent.
+	var restartConfig *config.Config
 	for <-reload {
 		reload <- false
 
 		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
+		c := restartConfig
+		restartConfig = nil
+		if c == nil {
+			var err error
+			c, err = loadConfig(inputFilters, outputFilters)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
 		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
-
-		if int64(c.Agent.Interval.Duration) <= 0 {
-			log.Fatalf("E! Agent interval must be positive, found %s",
-				c.Agent.Interval.Duration)
-		}
-
-		if int64(c.Agent.FlushInterval.Duration) <= 0 {
-			log.Fatalf("E! Agent flush_interval must be positive; found %s",
-				c.Agent.Interval.Duration)
-		}
 
 		ag, err := agent.NewAgent(c)
 		if err != nil {
@@ -188,20 +177,43 @@ func reloadLoop(
 		shutdown := make(chan struct{})
 		signals := make(chan os.Signal)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+		if *fWatchConfig {
+			watchConfig(signals, shutdown)
+		}
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
-					close(shutdown)
-				}
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt {
+						close(shutdown)
+						return
+					}
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config\n")
+						c, err := loadConfig(inputFilters, outputFilters)
+						if err != nil {
+							log.Printf("E! Error reloading config, keeping "+
+								"the running config: %s\n", err)
+							continue
+						}
+						err = ag.Reload(c)
+						if err == agent.ErrRestartRequired {
+							log.Printf("I! %s, restarting Telegraf\n", err)
+							restartConfig = c
+							<-reload
+							reload <- true
+							close(shutdown)
+							return
+						}
+						if err != nil {
+							log.Printf("E! Error reloading config, keeping "+
+								"the running config: %s\n", err)
+						}
+					}
+				case <-stop:
 					close(shutdown)
+					return
 				}
-			case <-stop:
-				close(shutdown)
 			}
 		}()
 
@@ -229,7 +241,114 @@ func reloadLoop(
 		}
 
 		ag.Run(shutdown)
+		signal.Stop(signals)
+	}
+}
+
+// loadConfig loads the config file and directory given on the command line.
+// If it cannot be loaded, the buffers of the outputs loaded so far are
+// released.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := readConfig(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// readConfig loads the config file and directory into c, and checks it.
+func readConfig(c *config.Config) error {
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
 	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return errors.New("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return errors.New("Error: no inputs found, did you provide a valid config file?")
+	}
+
+	if int64(c.Agent.Interval.Duration) <= 0 {
+		return fmt.Errorf("Agent interval must be positive, found %s",
+			c.Agent.Interval.Duration)
+	}
+
+	if int64(c.Agent.FlushInterval.Duration) <= 0 {
+		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+			c.Agent.Interval.Duration)
+	}
+	return nil
+}
+
+// watchConfig sends a SIGHUP to signals whenever the config file or a file
+// in the config directory changes, until shutdown is closed.
+func watchConfig(signals chan os.Signal, shutdown chan struct{}) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("E! Unable to watch the config: %s\n", err)
+		return
+	}
+
+	// the directory of the config file is watched rather than the file
+	// itself, as editors often replace the file when saving it.
+	var dirs []string
+	if *fConfig != "" {
+		dirs = append(dirs, filepath.Dir(*fConfig))
+	}
+	if *fConfigDirectory != "" {
+		dirs = append(dirs, *fConfigDirectory)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("E! Unable to watch %s: %s\n", dir, err)
+		}
+	}
+
+	isConfig := func(name string) bool {
+		if *fConfig != "" && filepath.Clean(name) == filepath.Clean(*fConfig) {
+			return true
+		}
+		return *fConfigDirectory != "" && strings.HasSuffix(name, ".conf") &&
+			filepath.Dir(name) == filepath.Clean(*fConfigDirectory)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// changes are usually made of several events, so the reload is
+		// delayed until the files have settled.
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-shutdown:
+				return
+			case event := <-watcher.Events:
+				if isConfig(event.Name) {
+					settle = time.After(time.Second)
+				}
+			case err := <-watcher.Errors:
+				log.Printf("E! Error watching the config: %s\n", err)
+			case <-settle:
+				settle = nil
+				select {
+				case signals <- syscall.SIGHUP:
+				case <-shutdown:
+					return
+				}
+			}
+		}
+	}()
 }
 
 func usageExit(rc int) {
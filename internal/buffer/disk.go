@@ -0,0 +1,658 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Fsync policies supported by DiskBuffer.
+const (
+	// FsyncAlways syncs the active segment after every call to Add.
+	FsyncAlways = "always"
+	// FsyncBatch syncs the active segment every time a batch is read from
+	// the buffer, and the read cursor every time a batch is committed.
+	FsyncBatch = "batch"
+	// FsyncNever leaves syncing to the operating system.
+	FsyncNever = "never"
+)
+
+const (
+	// Default size of a single segment file.
+	DefaultSegmentSize = 10 * 1024 * 1024
+
+	// Default maximum number of bytes kept on disk.
+	DefaultMaxSize = 100 * 1024 * 1024
+
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+
+	// record header: payload length + crc32 of the payload
+	headerSize = 8
+)
+
+// MetricBuffer is the interface implemented by the in-memory Buffer and by
+// the disk-backed DiskBuffer.
+type MetricBuffer interface {
+	IsEmpty() bool
+	Len() int
+	Add(metrics ...telegraf.Metric)
+	Batch(batchSize int) []telegraf.Metric
+}
+
+type segment struct {
+	id    uint64
+	size  int64
+	count int
+}
+
+// position is the position of the reader after a batch returned by Peek.
+type position struct {
+	// id is the segment the batch ends in, and offset the offset of the
+	// end of the batch in it.
+	id     uint64
+	offset int64
+	// count is the number of records of the batch in the segment id.
+	count int
+	// dropped is the number of records of the batch that could not be
+	// decoded.
+	dropped int64
+}
+
+// DiskBuffer is a write-ahead queue of metrics stored in segment files
+// within a directory. Metrics that are added to the buffer are appended to
+// the newest segment, and batches are read from the oldest one. The position
+// of the reader is stored in a cursor file, so that a restarted agent picks up
+// where the previous one left off.
+//
+// Batches are read with Peek, and only consumed by Commit once they have
+// been written, so that a batch is read again after a failed write or a
+// crash.
+//
+// A directory is only opened once per process: opening it again, as happens
+// when the configuration is reloaded, returns the same DiskBuffer, which is
+// closed once every user has called Close. Any user may Add metrics, but
+// only one at a time may read them with Peek and Commit, as the position of
+// the last batch peeked is kept by the buffer.
+type DiskBuffer struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	fsync       string
+
+	// segments are ordered from oldest to newest, the last one is always
+	// the active segment that is being written to.
+	segments []*segment
+	w        *os.File
+	// rOffset is the read position in the oldest segment.
+	rOffset int64
+	// peeked is the position after the last batch returned by Peek.
+	peeked *position
+
+	length int
+	size   int64
+
+	// refs is the number of users of the buffer, guarded by openMu.
+	refs int
+
+	mu sync.Mutex
+}
+
+var (
+	openMu sync.Mutex
+	// openBuffers holds the buffers currently open, by absolute directory.
+	openBuffers = make(map[string]*DiskBuffer)
+)
+
+// NewDiskBuffer opens the buffer stored in dir, creating it if necessary, and
+// replays any metrics left over from a previous run.
+//   maxSize is the maximum number of bytes kept on disk. When it is exceeded
+//   the oldest segment is dropped.
+//   segmentSize is the size at which a new segment file is started.
+// If dir is already open, the settings of the open buffer are kept.
+func NewDiskBuffer(dir string, maxSize, segmentSize int64, fsync string) (*DiskBuffer, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	openMu.Lock()
+	defer openMu.Unlock()
+	if b, ok := openBuffers[dir]; ok {
+		b.refs++
+		return b, nil
+	}
+
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	// keep at least two segments worth of data, otherwise dropping the
+	// oldest segment would drop everything.
+	if segmentSize > maxSize/2 {
+		segmentSize = maxSize / 2
+	}
+	switch fsync {
+	case "":
+		fsync = FsyncBatch
+	case FsyncAlways, FsyncBatch, FsyncNever:
+	default:
+		return nil, fmt.Errorf("invalid fsync policy %q, must be one of %q, %q or %q",
+			fsync, FsyncAlways, FsyncBatch, FsyncNever)
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	b := &DiskBuffer{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		fsync:       fsync,
+		refs:        1,
+	}
+	if err := b.open(); err != nil {
+		b.close()
+		return nil, err
+	}
+	openBuffers[dir] = b
+	return b, nil
+}
+
+// open loads the existing segments and the read cursor from disk.
+func (b *DiskBuffer) open() error {
+	ids, err := b.listSegments()
+	if err != nil {
+		return err
+	}
+
+	cursorID, cursorOffset := b.readCursor()
+	for _, id := range ids {
+		if id < cursorID {
+			// fully consumed by a previous run
+			os.Remove(b.segmentPath(id))
+			continue
+		}
+		offset := int64(0)
+		if id == cursorID {
+			offset = cursorOffset
+		}
+		seg, err := b.scanSegment(id, offset)
+		if err != nil {
+			return err
+		}
+		b.segments = append(b.segments, seg)
+		b.length += seg.count
+		b.size += seg.size
+	}
+
+	if len(b.segments) == 0 {
+		b.segments = append(b.segments, &segment{id: cursorID})
+		cursorOffset = 0
+	} else if b.segments[0].id != cursorID {
+		cursorOffset = 0
+	}
+
+	w, err := os.OpenFile(b.segmentPath(b.active().id),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	b.w = w
+	b.rOffset = cursorOffset
+
+	if b.length > 0 {
+		log.Printf("I! Replaying %d metrics from disk buffer %s", b.length, b.dir)
+	}
+	return nil
+}
+
+// scanSegment counts the valid records of a segment after offset. A torn
+// record at the end of the file, as left by a crash, is truncated.
+func (b *DiskBuffer) scanSegment(id uint64, offset int64) (*segment, error) {
+	path := b.segmentPath(id)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	seg := &segment{id: id}
+	r := bufio.NewReader(f)
+	pos := offset
+	for {
+		_, n, err := readRecord(r, info.Size()-pos)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("W! Disk buffer segment %s is corrupt at offset %d, "+
+				"truncating: %s", path, pos, err)
+			if err := os.Truncate(path, pos); err != nil {
+				return nil, err
+			}
+			break
+		}
+		pos += n
+		seg.count++
+	}
+	seg.size = pos
+	return seg, nil
+}
+
+func (b *DiskBuffer) listSegments() ([]uint64, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (b *DiskBuffer) readCursor() (uint64, int64) {
+	buf, err := ioutil.ReadFile(filepath.Join(b.dir, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	var id uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(buf), "%d %d", &id, &offset); err != nil {
+		log.Printf("W! Invalid disk buffer cursor in %s, replaying all segments",
+			b.dir)
+		return 0, 0
+	}
+	return id, offset
+}
+
+func (b *DiskBuffer) writeCursor() error {
+	path := filepath.Join(b.dir, cursorFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "%d %d\n", b.segments[0].id, b.rOffset)
+	if b.fsync != FsyncNever {
+		f.Sync()
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (b *DiskBuffer) segmentPath(id uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+func (b *DiskBuffer) active() *segment {
+	return b.segments[len(b.segments)-1]
+}
+
+// rotate starts a new active segment.
+func (b *DiskBuffer) rotate() error {
+	b.w.Sync()
+	b.w.Close()
+
+	seg := &segment{id: b.active().id + 1}
+	w, err := os.OpenFile(b.segmentPath(seg.id),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	b.w = w
+	b.segments = append(b.segments, seg)
+	return nil
+}
+
+// dropOldest removes the oldest segment, including any unread metrics in it.
+func (b *DiskBuffer) dropOldest() error {
+	if len(b.segments) == 1 {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+	MetricsDropped.Incr(int64(b.segments[0].count))
+	b.removeOldest()
+	return nil
+}
+
+// removeOldest removes the oldest segment, which must not be the active one.
+func (b *DiskBuffer) removeOldest() {
+	head := b.segments[0]
+	b.length -= head.count
+	b.size -= head.size
+	os.Remove(b.segmentPath(head.id))
+	b.segments = b.segments[1:]
+	b.rOffset = 0
+}
+
+// removeConsumed removes the oldest segments which have been fully read.
+func (b *DiskBuffer) removeConsumed() {
+	for len(b.segments) > 1 && b.segments[0].count == 0 {
+		b.removeOldest()
+	}
+}
+
+// IsEmpty returns true if DiskBuffer is empty.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the number of metrics in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.length
+}
+
+// Size returns the number of bytes the buffer occupies on disk.
+func (b *DiskBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Add appends metrics to the buffer. If the buffer grows larger than its
+// maximum size, then the oldest segment is dropped.
+//
+// Tracked metrics are accepted once they are stored, as they will be
+// delivered from disk from then on.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		MetricsWritten.Incr(1)
+		rec := encodeRecord(m)
+
+		active := b.active()
+		if active.size > 0 && active.size+int64(len(rec)) > b.segmentSize {
+			if err := b.rotate(); err != nil {
+				log.Printf("E! Unable to rotate disk buffer segment in %s: %s",
+					b.dir, err)
+				MetricsDropped.Incr(1)
+				m.Reject()
+				continue
+			}
+			active = b.active()
+		}
+
+		if _, err := b.w.Write(rec); err != nil {
+			log.Printf("E! Unable to write to disk buffer in %s: %s", b.dir, err)
+			MetricsDropped.Incr(1)
+			m.Reject()
+			continue
+		}
+		stored = append(stored, m)
+		active.size += int64(len(rec))
+		active.count++
+		b.size += int64(len(rec))
+		b.length++
+
+		for b.size > b.maxSize && len(b.segments) > 1 {
+			if err := b.dropOldest(); err != nil {
+				log.Printf("E! Unable to drop disk buffer segment in %s: %s",
+					b.dir, err)
+				break
+			}
+		}
+	}
+
+	if b.fsync == FsyncAlways {
+		b.w.Sync()
+	}
+	for _, m := range stored {
+		m.Accept()
+	}
+}
+
+// Batch returns a batch of metrics of size batchSize, removing them from the
+// buffer. The batch can be less than batchSize if the buffer holds fewer
+// metrics.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	out := b.Peek(batchSize)
+	b.Commit()
+	return out
+}
+
+// Peek returns a batch of metrics of size batchSize, without removing them
+// from the buffer: the same batch is returned by the next call to Peek
+// unless Commit is called in between. The batch can be less than batchSize
+// if the buffer holds fewer metrics.
+func (b *DiskBuffer) Peek(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.fsync == FsyncBatch {
+		b.w.Sync()
+	}
+	b.removeConsumed()
+	b.peeked = nil
+
+	out := make([]telegraf.Metric, 0, min(b.length, batchSize))
+	for i := 0; i < len(b.segments) && len(out) < batchSize; i++ {
+		seg := b.segments[i]
+		if seg.count == 0 {
+			// only the active segment can be empty once consumed segments
+			// are removed.
+			break
+		}
+		offset := int64(0)
+		if i == 0 {
+			offset = b.rOffset
+		}
+
+		p := &position{id: seg.id, offset: offset}
+		metrics, err := b.readSegment(seg, p, batchSize-len(out))
+		out = append(out, metrics...)
+		if p.count > 0 {
+			b.peeked = p
+		}
+		if err == nil {
+			continue
+		}
+
+		if i > 0 || p.count > 0 {
+			// the corrupt record is dropped once the records before it
+			// are committed.
+			break
+		}
+		log.Printf("E! Unable to read from disk buffer in %s, dropping "+
+			"%d metrics: %s", b.dir, seg.count, err)
+		if err := b.dropOldest(); err != nil {
+			break
+		}
+		i = -1
+	}
+	return out
+}
+
+// readSegment reads at most max records of seg from the position p, which
+// is advanced past the records read.
+func (b *DiskBuffer) readSegment(seg *segment, p *position, max int) ([]telegraf.Metric, error) {
+	f, err := os.Open(b.segmentPath(seg.id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(p.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var out []telegraf.Metric
+	r := bufio.NewReader(f)
+	for len(out) < max && p.count < seg.count {
+		payload, n, err := readRecord(r, info.Size()-p.offset)
+		if err != nil {
+			return out, err
+		}
+		p.offset += n
+		p.count++
+
+		m, err := decodeRecord(payload)
+		if err != nil {
+			log.Printf("E! Unable to decode metric from disk buffer in %s: %s",
+				b.dir, err)
+			p.dropped++
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Commit removes the batch returned by the last call to Peek from the
+// buffer, and stores the new read position.
+func (b *DiskBuffer) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := b.peeked
+	b.peeked = nil
+	if p == nil {
+		return
+	}
+
+	// the segments before the end of the batch have been fully read,
+	// unless they have been dropped since.
+	for len(b.segments) > 1 && b.segments[0].id < p.id {
+		b.removeOldest()
+	}
+	if head := b.segments[0]; head.id == p.id {
+		head.count -= p.count
+		b.length -= p.count
+		b.rOffset = p.offset
+	}
+	MetricsDropped.Incr(p.dropped)
+	b.removeConsumed()
+
+	if err := b.writeCursor(); err != nil {
+		log.Printf("E! Unable to store disk buffer cursor in %s: %s", b.dir, err)
+	}
+}
+
+// Close syncs and closes the segment files once the buffer is no longer
+// used.
+func (b *DiskBuffer) Close() error {
+	openMu.Lock()
+	defer openMu.Unlock()
+	b.refs--
+	if b.refs > 0 {
+		return nil
+	}
+	delete(openBuffers, b.dir)
+	return b.close()
+}
+
+func (b *DiskBuffer) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.w != nil {
+		b.w.Sync()
+		err := b.w.Close()
+		b.w = nil
+		return err
+	}
+	return nil
+}
+
+// encodeRecord encodes a metric as a record of the form:
+//   length(4) crc32(4) type(1) line-protocol
+func encodeRecord(m telegraf.Metric) []byte {
+	line := m.Serialize()
+	rec := make([]byte, headerSize+1+len(line))
+	rec[headerSize] = byte(m.Type())
+	copy(rec[headerSize+1:], line)
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(rec)-headerSize))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(rec[headerSize:]))
+	return rec
+}
+
+// readRecord reads the payload of the next record, returning io.EOF if there
+// are no more records, and the number of bytes consumed. remaining is the
+// number of bytes left in the segment, a record claiming to be longer is
+// corrupt.
+func readRecord(r *bufio.Reader, remaining int64) ([]byte, int64, error) {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(r, header)
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, int64(n), fmt.Errorf("short record header: %s", err)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if int64(length) > remaining-headerSize {
+		return nil, int64(n), fmt.Errorf("record length %d exceeds the segment", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, int64(n), fmt.Errorf("short record: %s", err)
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, int64(n), fmt.Errorf("checksum mismatch")
+	}
+	return payload, int64(headerSize + len(payload)), nil
+}
+
+func decodeRecord(payload []byte) (telegraf.Metric, error) {
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("record too short")
+	}
+	mType := telegraf.ValueType(payload[0])
+	metrics, err := metric.Parse(payload[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, found %d", len(metrics))
+	}
+	m := metrics[0]
+	return metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), mType)
+}
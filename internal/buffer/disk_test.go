@@ -0,0 +1,272 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, dir string, maxSize, segmentSize int64) *DiskBuffer {
+	b, err := NewDiskBuffer(dir, maxSize, segmentSize, FsyncNever)
+	require.NoError(t, err)
+	return b
+}
+
+func TestDiskBufferBasicFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0, 0)
+	defer b.Close()
+
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Len())
+
+	b.Add(metricList...)
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 5, b.Len())
+	assert.True(t, b.Size() > 0)
+
+	batch := b.Batch(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, "mymetric2", batch[1].Name())
+	assert.Equal(t, 3, b.Len())
+
+	batch = b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric5", batch[2].Name())
+	assert.True(t, b.IsEmpty())
+	assert.Len(t, b.Batch(10), 0)
+}
+
+func TestDiskBufferPreservesMetric(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0, 0)
+	defer b.Close()
+
+	now := time.Unix(1500000000, 123)
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage": 42.5, "count": int64(3), "ok": true, "s": "x"},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	b.Add(m)
+
+	batch := b.Batch(1)
+	require.Len(t, batch, 1)
+	assert.Equal(t, m.Name(), batch[0].Name())
+	assert.Equal(t, m.Tags(), batch[0].Tags())
+	assert.Equal(t, m.Fields(), batch[0].Fields())
+	assert.Equal(t, now.UnixNano(), batch[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0, 0)
+	b.Add(metricList...)
+	batch := b.Batch(2)
+	require.Len(t, batch, 2)
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 0, 0)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+
+	batch = b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+	assert.Equal(t, "mymetric4", batch[1].Name())
+	assert.Equal(t, "mymetric5", batch[2].Name())
+}
+
+func TestDiskBufferPeekCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m := testutil.TestMetric(1, "mymetric")
+	recSize := int64(len(encodeRecord(m)))
+
+	// batches span segments of two metrics
+	b := newTestDiskBuffer(t, dir, 100*recSize, 2*recSize)
+	b.Add(metricList...)
+	size := b.Size()
+
+	batch := b.Peek(3)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, 5, b.Len())
+
+	// the batch is read again until it is committed
+	batch = b.Peek(3)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, size, b.Size())
+
+	b.Commit()
+	assert.Equal(t, 2, b.Len())
+	// a commit without peek does nothing
+	b.Commit()
+	assert.Equal(t, 2, b.Len())
+
+	// peeked metrics are read again after a restart
+	batch = b.Peek(1)
+	require.Len(t, batch, 1)
+	assert.Equal(t, "mymetric4", batch[0].Name())
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 100*recSize, 2*recSize)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+	batch = b.Peek(10)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "mymetric4", batch[0].Name())
+	assert.Equal(t, "mymetric5", batch[1].Name())
+}
+
+func TestDiskBufferSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m := testutil.TestMetric(1, "mymetric")
+	recSize := int64(len(encodeRecord(m)))
+
+	// two metrics per segment
+	b := newTestDiskBuffer(t, dir, 100*recSize, 2*recSize)
+	defer b.Close()
+	for i := 0; i < 5; i++ {
+		b.Add(m)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	assert.Len(t, b.Batch(5), 5)
+	assert.True(t, b.IsEmpty())
+
+	// consumed segments are removed on the next batch
+	b.Add(m)
+	assert.Len(t, b.Batch(5), 1)
+	files, err = filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestDiskBufferDropsOldestSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var recSize int64
+	for _, m := range metricList {
+		if n := int64(len(encodeRecord(m))); n > recSize {
+			recSize = n
+		}
+	}
+
+	MetricsDropped.Set(0)
+	b := newTestDiskBuffer(t, dir, 4*recSize, 2*recSize)
+	defer b.Close()
+
+	b.Add(metricList...)
+	assert.Equal(t, int64(2), MetricsDropped.Get())
+	assert.Equal(t, 3, b.Len())
+	assert.True(t, b.Size() <= 4*recSize)
+
+	batch := b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+}
+
+func TestDiskBufferTruncatesTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0, 0)
+	b.Add(metricList[:2]...)
+	require.NoError(t, b.Close())
+
+	// simulate a crash in the middle of writing a record
+	path := b.segmentPath(0)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write(encodeRecord(metricList[2])[:10])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, dir, 0, 0)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+
+	b.Add(metricList[3])
+	batch := b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric4", batch[2].Name())
+}
+
+func TestDiskBufferSharedWithinProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b1 := newTestDiskBuffer(t, dir, 0, 0)
+	b2 := newTestDiskBuffer(t, dir, 0, 0)
+	assert.True(t, b1 == b2)
+
+	b1.Add(metricList...)
+	require.NoError(t, b1.Close())
+
+	// still open for the second user
+	assert.Equal(t, 5, b2.Len())
+	assert.Len(t, b2.Batch(10), 5)
+	require.NoError(t, b2.Close())
+
+	b3 := newTestDiskBuffer(t, dir, 0, 0)
+	defer b3.Close()
+	assert.False(t, b3 == b1)
+}
+
+func TestDiskBufferRejectsOversizedRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskbuffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0, 0)
+	b.Add(metricList[:2]...)
+	require.NoError(t, b.Close())
+
+	// a corrupt header claiming a 4GiB record
+	path := b.segmentPath(0)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, dir, 0, 0)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+	assert.Len(t, b.Batch(10), 2)
+}
@@ -18,7 +18,9 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/buffer"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -56,6 +58,10 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// fingerprints holds the canonical configuration of each plugin, keyed
+	// by its running plugin, to find the plugins that changed on reload.
+	fingerprints map[interface{}]string
 }
 
 func NewConfig() *Config {
@@ -65,6 +71,8 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+
+			HealthOutputFailureThreshold: internal.Duration{Duration: 5 * time.Minute},
 		},
 
 		Tags:          make(map[string]string),
@@ -73,6 +81,7 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		fingerprints:  make(map[interface{}]string),
 	}
 	return c
 }
@@ -139,6 +148,14 @@ type AgentConfig struct {
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// HTTPAPIAddress is the address the agent API listens on, the API is
+	// disabled when it is empty.
+	HTTPAPIAddress string `toml:"http_api_address"`
+
+	// HealthOutputFailureThreshold is how long an output can fail to write
+	// before the agent is reported as unhealthy.
+	HealthOutputFailureThreshold internal.Duration `toml:"health_output_failure_threshold"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -159,6 +176,17 @@ func (c *Config) OutputNames() []string {
 	return name
 }
 
+// Close releases the buffers of the outputs of a configuration that is not
+// run, such as one whose reload failed.
+func (c *Config) Close() {
+	for _, o := range c.Outputs {
+		if err := o.Close(); err != nil {
+			log.Printf("E! Error closing buffer of output [%s]: %s\n",
+				o.Name, err)
+		}
+	}
+}
+
 // ListTags returns a string of tags specified in the config,
 // line-protocol style
 func (c *Config) ListTags() string {
@@ -252,6 +280,13 @@ var header = `# Telegraf Configuration
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP API reporting the health and state of the agent,
+  ## ie "localhost:8180".  The API is disabled when empty.
+  # http_api_address = ""
+  ## The /health route of the API reports the agent as unhealthy when an
+  ## output has been failing to write for longer than this.
+  # health_output_failure_threshold = "5m"
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -708,7 +743,61 @@ func parseFile(fpath string) (*ast.Table, error) {
 		}
 	}
 
-	return toml.Parse(contents)
+	tbl, err := toml.Parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveSecrets(tbl); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// resolveSecrets replaces the secret references in the string values of tbl
+// by their secret.
+func resolveSecrets(tbl *ast.Table) error {
+	for _, field := range tbl.Fields {
+		switch v := field.(type) {
+		case *ast.KeyValue:
+			if err := resolveValue(v.Value); err != nil {
+				return fmt.Errorf("line %d: %s", v.Line, err)
+			}
+		case *ast.Table:
+			if err := resolveSecrets(v); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func resolveValue(v ast.Value) error {
+	switch v := v.(type) {
+	case *ast.String:
+		if !secret.HasReference(v.Value) {
+			return nil
+		}
+		value, err := secret.Resolve(v.Value)
+		if err != nil {
+			return err
+		}
+		v.Value = value
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := resolveValue(elem); err != nil {
+				return err
+			}
+		}
+	case *ast.Table:
+		return resolveSecrets(v)
+	}
+	return nil
 }
 
 func (c *Config) addAggregator(name string, table *ast.Table) error {
@@ -717,6 +806,7 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	fp := fingerprint("aggregators."+name, table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
@@ -727,7 +817,13 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	if err := initPlugin(aggregator); err != nil {
+		return err
+	}
+
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.fingerprints[ra] = fp
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -737,6 +833,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	fp := fingerprint("processors."+name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
@@ -747,12 +844,17 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return err
 	}
 
+	if err := initPlugin(processor); err != nil {
+		return err
+	}
+
 	rf := &models.RunningProcessor{
 		Name:      name,
 		Processor: processor,
 		Config:    processorConfig,
 	}
 
+	c.fingerprints[rf] = fp
 	c.Processors = append(c.Processors, rf)
 	return nil
 }
@@ -766,6 +868,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	fp := fingerprint("outputs."+name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -782,17 +885,58 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	if err := c.checkBufferPath(name, outputConfig.BufferPath); err != nil {
+		return err
+	}
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
 
+	if err := initPlugin(output); err != nil {
+		return err
+	}
+
+	batchSize := c.Agent.MetricBatchSize
+	if outputConfig.MetricBatchSize != 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
+	bufferLimit := c.Agent.MetricBufferLimit
+	if outputConfig.MetricBufferLimit != 0 {
+		bufferLimit = outputConfig.MetricBufferLimit
+	}
+
 	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+		batchSize, bufferLimit)
+	c.fingerprints[ro] = fp
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
 
+// checkBufferPath returns an error if the disk buffer directory path is
+// already used by another output: batches are read from a disk buffer by a
+// single output.
+func (c *Config) checkBufferPath(name, path string) error {
+	if path == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, o := range c.Outputs {
+		if o.Config.BufferPath == "" {
+			continue
+		}
+		other, err := filepath.Abs(o.Config.BufferPath)
+		if err == nil && other == abs {
+			return fmt.Errorf("buffer_path %q of output %s is already used "+
+				"by output %s", path, name, o.Name)
+		}
+	}
+	return nil
+}
+
 func (c *Config) addInput(name string, table *ast.Table) error {
 	if len(c.InputFilters) > 0 && !sliceContains(name, c.InputFilters) {
 		return nil
@@ -807,16 +951,30 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
-
-	// If the input has a SetParser function, then this means it can accept
-	// arbitrary types of input, so build the parser and set it.
-	switch t := input.(type) {
-	case parsers.ParserInput:
-		parser, err := buildParser(name, table)
+	fp := fingerprint("inputs."+name, table)
+
+	// If the input has a SetParser or SetParserFunc function, then this
+	// means it can accept arbitrary types of input, so build the parser and
+	// set it.
+	_, isParserInput := input.(parsers.ParserInput)
+	_, isParserFuncInput := input.(parsers.ParserFuncInput)
+	if isParserInput || isParserFuncInput {
+		pc, err := getParserConfig(name, table)
 		if err != nil {
 			return err
 		}
-		t.SetParser(parser)
+		if t, ok := input.(parsers.ParserInput); ok {
+			parser, err := parsers.NewParser(pc)
+			if err != nil {
+				return err
+			}
+			t.SetParser(parser)
+		}
+		if t, ok := input.(parsers.ParserFuncInput); ok {
+			t.SetParserFunc(func() (parsers.Parser, error) {
+				return parsers.NewParser(pc)
+			})
+		}
 	}
 
 	pluginConfig, err := buildInput(name, table)
@@ -828,11 +986,26 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return err
 	}
 
+	if err := initPlugin(input); err != nil {
+		return err
+	}
+
 	rp := models.NewRunningInput(input, pluginConfig)
+	c.fingerprints[rp] = fp
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// initPlugin calls the Init function of plugins implementing
+// telegraf.Initializer, so that an invalid configuration is reported when it
+// is loaded.
+func initPlugin(plugin interface{}) error {
+	if p, ok := plugin.(telegraf.Initializer); ok {
+		return p.Init()
+	}
+	return nil
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -889,6 +1062,48 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["grace_periods"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				if v < 0 {
+					return nil, fmt.Errorf("grace_periods must not be negative (%s)", name)
+				}
+				conf.GracePeriods = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["grace_metric_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				if v < 1 {
+					return nil, fmt.Errorf("grace_metric_limit must be positive (%s)", name)
+				}
+				conf.GraceMetricLimit = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["use_arrival_time"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				conf.UseArrivalTime, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -924,6 +1139,9 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
+	delete(tbl.Fields, "grace_periods")
+	delete(tbl.Fields, "grace_metric_limit")
+	delete(tbl.Fields, "use_arrival_time")
 	delete(tbl.Fields, "drop_original")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
@@ -1172,10 +1390,10 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	return cp, nil
 }
 
-// buildParser grabs the necessary entries from the ast.Table for creating
-// a parsers.Parser object, and creates it, which can then be added onto
-// an Input object.
-func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
+// getParserConfig grabs the necessary entries from the ast.Table for
+// creating a parsers.Parser object, which can then be added onto an Input
+// object.
+func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	c := &parsers.Config{}
 
 	if node, ok := tbl.Fields["data_format"]; ok {
@@ -1225,6 +1443,42 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["json_string_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.JSONStringFields = append(c.JSONStringFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_query"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONQuery = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_time_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimeKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_time_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimeFormat = str.Value
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["data_type"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1261,18 +1515,185 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["csv_header_row_count"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVHeaderRowCount = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_skip_rows"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVSkipRows = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_skip_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVSkipColumns = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_column_names"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumnNames = append(c.CSVColumnNames, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_column_types"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumnTypes = append(c.CSVColumnTypes, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_delimiter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVDelimiter = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_comment"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVComment = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_trim_space"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVTrimSpace = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_tag_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVTagColumns = append(c.CSVTagColumns, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_measurement_column"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVMeasurementColumn = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_column"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampColumn = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dropwizard_metric_registry_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.DropwizardMetricRegistryPath = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["logfmt_time_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.LogfmtTimeKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["logfmt_time_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.LogfmtTimeFormat = str.Value
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "separator")
 	delete(tbl.Fields, "templates")
 	delete(tbl.Fields, "tag_keys")
+	delete(tbl.Fields, "json_string_fields")
+	delete(tbl.Fields, "json_query")
+	delete(tbl.Fields, "json_time_key")
+	delete(tbl.Fields, "json_time_format")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
 	delete(tbl.Fields, "collectd_typesdb")
-
-	return parsers.NewParser(c)
+	delete(tbl.Fields, "csv_header_row_count")
+	delete(tbl.Fields, "csv_skip_rows")
+	delete(tbl.Fields, "csv_skip_columns")
+	delete(tbl.Fields, "csv_column_names")
+	delete(tbl.Fields, "csv_column_types")
+	delete(tbl.Fields, "csv_delimiter")
+	delete(tbl.Fields, "csv_comment")
+	delete(tbl.Fields, "csv_trim_space")
+	delete(tbl.Fields, "csv_tag_columns")
+	delete(tbl.Fields, "csv_measurement_column")
+	delete(tbl.Fields, "csv_timestamp_column")
+	delete(tbl.Fields, "csv_timestamp_format")
+	delete(tbl.Fields, "dropwizard_metric_registry_path")
+	delete(tbl.Fields, "logfmt_time_key")
+	delete(tbl.Fields, "logfmt_time_format")
+
+	return c, nil
 }
 
 // buildSerializer grabs the necessary entries from the ast.Table for creating
@@ -1309,6 +1730,18 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["graphite_tag_support"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.GraphiteTagSupport = v
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["json_timestamp_units"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1325,10 +1758,95 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["json_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_name_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONNameKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_timestamp_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimestampKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_tag_prefix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTagPrefix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_field_prefix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONFieldPrefix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_flatten"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.JSONFlatten = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_per_field"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.JSONPerField = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_batch"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.JSONBatch = v
+			}
+		}
+	}
+
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
+	delete(tbl.Fields, "graphite_tag_support")
 	delete(tbl.Fields, "json_timestamp_units")
+	delete(tbl.Fields, "json_timestamp_format")
+	delete(tbl.Fields, "json_name_key")
+	delete(tbl.Fields, "json_timestamp_key")
+	delete(tbl.Fields, "json_tag_prefix")
+	delete(tbl.Fields, "json_field_prefix")
+	delete(tbl.Fields, "json_flatten")
+	delete(tbl.Fields, "json_per_field")
+	delete(tbl.Fields, "json_batch")
 	return serializers.NewSerializer(c)
 }
 
@@ -1352,5 +1870,185 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["buffer_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferPath = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.BufferMaxSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_segment_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.BufferSegmentSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_fsync"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				switch str.Value {
+				case buffer.FsyncAlways, buffer.FsyncBatch, buffer.FsyncNever:
+					oc.BufferFsync = str.Value
+				default:
+					return nil, fmt.Errorf("invalid buffer_fsync %q for output %s",
+						str.Value, name)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				if dur <= 0 {
+					return nil, fmt.Errorf("flush_interval of output %s must be positive; found %s",
+						name, str.Value)
+				}
+				oc.FlushInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.FlushJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				if v <= 0 {
+					return nil, fmt.Errorf("metric_batch_size of output %s must be positive; found %d",
+						name, v)
+				}
+				oc.MetricBatchSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_buffer_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				if v <= 0 {
+					return nil, fmt.Errorf("metric_buffer_limit of output %s must be positive; found %d",
+						name, v)
+				}
+				oc.MetricBufferLimit = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_initial_delay"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.InitialDelay = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_delay"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.MaxDelay = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.Jitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_attempts"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.MaxAttempts = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_threshold"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.CircuitBreakerThreshold = v
+			}
+		}
+	}
+
+	delete(tbl.Fields, "buffer_path")
+	delete(tbl.Fields, "buffer_max_size")
+	delete(tbl.Fields, "buffer_segment_size")
+	delete(tbl.Fields, "buffer_fsync")
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_buffer_limit")
+	delete(tbl.Fields, "retry_initial_delay")
+	delete(tbl.Fields, "retry_max_delay")
+	delete(tbl.Fields, "retry_jitter")
+	delete(tbl.Fields, "retry_max_attempts")
+	delete(tbl.Fields, "circuit_breaker_threshold")
 	return oc, nil
 }
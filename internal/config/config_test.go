@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -10,7 +11,9 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/discard"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/processors/script"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -174,3 +177,119 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_ProcessorInitError(t *testing.T) {
+	f, err := ioutil.TempFile("", "telegraf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+[[processors.script]]
+  source = "fields.x = "
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := NewConfig()
+	err = c.LoadConfig(f.Name())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid script: line 1:12")
+	}
+}
+
+func TestConfig_OutputOverrides(t *testing.T) {
+	f, err := ioutil.TempFile("", "telegraf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+[agent]
+  flush_interval = "10s"
+  metric_batch_size = 1000
+  metric_buffer_limit = 10000
+
+[[outputs.discard]]
+
+[[outputs.discard]]
+  flush_interval = "1m"
+  flush_jitter = "5s"
+  metric_batch_size = 5000
+  metric_buffer_limit = 50000
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig(f.Name()))
+	if assert.Len(t, c.Outputs, 2) {
+		assert.Equal(t, time.Duration(0), c.Outputs[0].Config.FlushInterval)
+		assert.Equal(t, 1000, c.Outputs[0].MetricBatchSize)
+		assert.Equal(t, 10000, c.Outputs[0].MetricBufferLimit)
+
+		assert.Equal(t, time.Minute, c.Outputs[1].Config.FlushInterval)
+		assert.Equal(t, 5*time.Second, c.Outputs[1].Config.FlushJitter)
+		assert.Equal(t, 5000, c.Outputs[1].MetricBatchSize)
+		assert.Equal(t, 50000, c.Outputs[1].MetricBufferLimit)
+	}
+}
+
+func TestConfig_SharedBufferPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := ioutil.TempFile("", "telegraf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+[[outputs.discard]]
+  buffer_path = "` + dir + `"
+
+[[outputs.discard]]
+  buffer_path = "` + dir + `/"
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := NewConfig()
+	err = c.LoadConfig(f.Name())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is already used by output discard")
+	}
+	c.Close()
+}
+
+func TestConfig_SecretReferences(t *testing.T) {
+	secretFile, err := ioutil.TempFile("", "secret")
+	assert.NoError(t, err)
+	defer os.Remove(secretFile.Name())
+	_, err = secretFile.WriteString("memcached-secret\n")
+	assert.NoError(t, err)
+	assert.NoError(t, secretFile.Close())
+
+	os.Setenv("TEST_MEMCACHED_HOST", "localhost")
+	defer os.Unsetenv("TEST_MEMCACHED_HOST")
+
+	f, err := ioutil.TempFile("", "telegraf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+[[inputs.memcached]]
+  servers = ["@{env:TEST_MEMCACHED_HOST}:@{file:` + secretFile.Name() + `}"]
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig(f.Name()))
+	if assert.Len(t, c.Inputs, 1) {
+		m := c.Inputs[0].Input.(*memcached.Memcached)
+		assert.Equal(t, []string{"localhost:memcached-secret"}, m.Servers)
+	}
+
+	// a secret which cannot be resolved fails the load
+	os.Unsetenv("TEST_MEMCACHED_HOST")
+	c = NewConfig()
+	err = c.LoadConfig(f.Name())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: could not resolve secret @{env:TEST_MEMCACHED_HOST}")
+	}
+}
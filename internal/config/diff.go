@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// Diff holds the plugins that differ between the running configuration and
+// a newly loaded one.
+type Diff struct {
+	AddedInputs        []*models.RunningInput
+	RemovedInputs      []*models.RunningInput
+	AddedOutputs       []*models.RunningOutput
+	RemovedOutputs     []*models.RunningOutput
+	AddedAggregators   []*models.RunningAggregator
+	RemovedAggregators []*models.RunningAggregator
+
+	// UnusedOutputs are the outputs of the new configuration that were
+	// replaced by an identical running output. They were never connected,
+	// but must be closed to release their buffer.
+	UnusedOutputs []*models.RunningOutput
+}
+
+// AgentChanged returns true when the [agent] or [global_tags] settings of c
+// differ from the running configuration. These apply to every plugin, so
+// such a change cannot be reconciled.
+func (c *Config) AgentChanged(running *Config) bool {
+	return !reflect.DeepEqual(c.Agent, running.Agent) ||
+		!reflect.DeepEqual(c.Tags, running.Tags)
+}
+
+// Reconcile compares c with the running configuration. Every plugin of c
+// that is configured identically to a running plugin is replaced by the
+// running one, so that it keeps its state and buffered metrics, and the
+// plugins that were added or removed are returned.
+//
+// A plugin whose configuration changed is reported as removed and added.
+func (c *Config) Reconcile(running *Config) *Diff {
+	d := &Diff{}
+
+	// running plugins by fingerprint, in order, so that identical plugins
+	// configured more than once are matched one to one.
+	unmatched := make(map[string][]interface{})
+	for _, p := range running.plugins() {
+		fp := running.fingerprints[p]
+		unmatched[fp] = append(unmatched[fp], p)
+	}
+	match := func(p interface{}) interface{} {
+		fp := c.fingerprints[p]
+		if len(unmatched[fp]) == 0 {
+			return nil
+		}
+		old := unmatched[fp][0]
+		unmatched[fp] = unmatched[fp][1:]
+		delete(c.fingerprints, p)
+		c.fingerprints[old] = fp
+		return old
+	}
+
+	for i, input := range c.Inputs {
+		if old := match(input); old != nil {
+			c.Inputs[i] = old.(*models.RunningInput)
+		} else {
+			d.AddedInputs = append(d.AddedInputs, input)
+		}
+	}
+	for i, output := range c.Outputs {
+		if old := match(output); old != nil {
+			c.Outputs[i] = old.(*models.RunningOutput)
+			d.UnusedOutputs = append(d.UnusedOutputs, output)
+		} else {
+			d.AddedOutputs = append(d.AddedOutputs, output)
+		}
+	}
+	for i, aggregator := range c.Aggregators {
+		if old := match(aggregator); old != nil {
+			c.Aggregators[i] = old.(*models.RunningAggregator)
+		} else {
+			d.AddedAggregators = append(d.AddedAggregators, aggregator)
+		}
+	}
+	for i, processor := range c.Processors {
+		if old := match(processor); old != nil {
+			c.Processors[i] = old.(*models.RunningProcessor)
+		}
+	}
+
+	for _, p := range running.plugins() {
+		fp := running.fingerprints[p]
+		if len(unmatched[fp]) == 0 || unmatched[fp][0] != p {
+			continue
+		}
+		unmatched[fp] = unmatched[fp][1:]
+		switch p := p.(type) {
+		case *models.RunningInput:
+			d.RemovedInputs = append(d.RemovedInputs, p)
+		case *models.RunningOutput:
+			d.RemovedOutputs = append(d.RemovedOutputs, p)
+		case *models.RunningAggregator:
+			d.RemovedAggregators = append(d.RemovedAggregators, p)
+		}
+	}
+	return d
+}
+
+// plugins returns all the running plugins of c.
+func (c *Config) plugins() []interface{} {
+	var plugins []interface{}
+	for _, p := range c.Inputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Outputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Aggregators {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Processors {
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
+
+// fingerprint returns a canonical representation of the configuration of a
+// plugin, which does not depend on the order of its settings, comments or
+// whitespace. It must be computed before the table is consumed by the
+// build functions.
+func fingerprint(name string, tbl *ast.Table) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	writeTable(&buf, tbl)
+	return buf.String()
+}
+
+func writeTable(buf *bytes.Buffer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf.WriteByte('{')
+	for _, k := range keys {
+		buf.WriteString(strconv.Quote(k))
+		buf.WriteByte('=')
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			writeValue(buf, v.Value)
+		case *ast.Table:
+			writeTable(buf, v)
+		case []*ast.Table:
+			buf.WriteByte('[')
+			for _, t := range v {
+				writeTable(buf, t)
+			}
+			buf.WriteByte(']')
+		}
+		buf.WriteByte(';')
+	}
+	buf.WriteByte('}')
+}
+
+func writeValue(buf *bytes.Buffer, v ast.Value) {
+	switch v := v.(type) {
+	case *ast.Array:
+		buf.WriteByte('[')
+		for _, elem := range v.Value {
+			writeValue(buf, elem)
+			buf.WriteByte(',')
+		}
+		buf.WriteByte(']')
+	case *ast.Table:
+		writeTable(buf, v)
+	case *ast.String:
+		// the value holds the resolved secrets, so that a plugin is restarted
+		// when one of its secrets changes; only its hash is kept.
+		fmt.Fprintf(buf, "%x", sha256.Sum256([]byte(v.Value)))
+	default:
+		buf.WriteString(v.Source())
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestConfig(t *testing.T, toml string) *Config {
+	f, err := ioutil.TempFile("", "telegraf")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(toml)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig(f.Name()))
+	return c
+}
+
+func TestReconcile(t *testing.T) {
+	running := loadTestConfig(t, `
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["metricname1"]
+
+[[inputs.memcached]]
+  servers = ["192.168.1.1"]
+
+[[inputs.exec]]
+  commands = ["/bin/true"]
+`)
+	c := loadTestConfig(t, `
+[[inputs.exec]]
+  commands = ["/bin/false"]
+
+# comments, whitespace and the order of settings are not changes
+[[inputs.memcached]]
+  namepass = [ "metricname1" ]
+  servers = ["localhost"]
+
+[[inputs.memcached]]
+  servers = ["192.168.1.1"]
+`)
+
+	assert.False(t, c.AgentChanged(running))
+	d := c.Reconcile(running)
+
+	require.Len(t, c.Inputs, 3)
+	var kept int
+	for _, input := range c.Inputs {
+		for _, old := range running.Inputs {
+			if input == old {
+				kept++
+			}
+		}
+	}
+	assert.Equal(t, 2, kept)
+
+	require.Len(t, d.AddedInputs, 1)
+	assert.Equal(t, "exec", d.AddedInputs[0].Config.Name)
+	require.Len(t, d.RemovedInputs, 1)
+	assert.Equal(t, "exec", d.RemovedInputs[0].Config.Name)
+
+	// the reconciled config can be reconciled again
+	d = loadTestConfig(t, `
+[[inputs.memcached]]
+  servers = ["192.168.1.1"]
+`).Reconcile(c)
+	assert.Len(t, d.AddedInputs, 0)
+	assert.Len(t, d.RemovedInputs, 2)
+}
+
+func TestReconcileDuplicatePlugins(t *testing.T) {
+	conf := `
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+`
+	running := loadTestConfig(t, conf)
+	c := loadTestConfig(t, conf)
+
+	d := c.Reconcile(running)
+	assert.Len(t, d.AddedInputs, 0)
+	assert.Len(t, d.RemovedInputs, 0)
+	assert.True(t, c.Inputs[0] == running.Inputs[0])
+	assert.True(t, c.Inputs[1] == running.Inputs[1])
+}
+
+func TestAgentChanged(t *testing.T) {
+	running := loadTestConfig(t, `
+[agent]
+  interval = "10s"
+
+[[inputs.memcached]]
+`)
+	c := loadTestConfig(t, `
+[agent]
+  interval = "20s"
+
+[[inputs.memcached]]
+`)
+	assert.True(t, c.AgentChanged(running))
+
+	c = loadTestConfig(t, `
+[global_tags]
+  dc = "us-east-1"
+
+[agent]
+  interval = "10s"
+
+[[inputs.memcached]]
+`)
+	assert.True(t, c.AgentChanged(running))
+}
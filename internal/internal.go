@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"os/exec"
@@ -155,6 +156,46 @@ func GetTLSConfig(
 	return t, nil
 }
 
+// GetServerTLSConfig gets a tls.Config object for a server from the given
+// cert and key files. When allowedCACerts are given, clients must present a
+// certificate signed by one of these CAs.
+// If the cert and key are blank, returns a nil pointer.
+func GetServerTLSConfig(
+	TLSCert, TLSKey string,
+	TLSAllowedCACerts []string,
+) (*tls.Config, error) {
+	if TLSCert == "" && TLSKey == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(TLSCert, TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not load TLS server key/certificate from %s:%s: %s",
+			TLSKey, TLSCert, err)
+	}
+
+	t := &tls.Config{
+		Certificates:  []tls.Certificate{cert},
+		Renegotiation: tls.RenegotiateNever,
+	}
+
+	if len(TLSAllowedCACerts) > 0 {
+		clientPool := x509.NewCertPool()
+		for _, ca := range TLSAllowedCACerts {
+			caCert, err := ioutil.ReadFile(ca)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load TLS client CA: %s", err)
+			}
+			clientPool.AppendCertsFromPEM(caCert)
+		}
+		t.ClientCAs = clientPool
+		t.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return t, nil
+}
+
 // SnakeCase converts the given string to snake case following the Golang format:
 // acronyms are converted to lower-case and preceded by an underscore.
 func SnakeCase(in string) string {
@@ -172,6 +213,122 @@ func SnakeCase(in string) string {
 	return string(out)
 }
 
+// LookupPath returns the value found at path in a decoded JSON document, the
+// path being the keys of objects and the indices of arrays separated by
+// dots, such as "data.0.metrics".
+func LookupPath(doc interface{}, path string) (interface{}, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("invalid index %q", key)
+			}
+			doc = v[i]
+		default:
+			doc = nil
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("%q not found", key)
+		}
+	}
+	return doc, nil
+}
+
+// ParseTimestamp parses a timestamp in format, which is either "unix",
+// "unix_ms", "unix_us" or "unix_ns" for a possibly fractional number of
+// seconds, milliseconds, microseconds or nanoseconds since the epoch, or a Go
+// time layout.
+func ParseTimestamp(value string, format string) (time.Time, error) {
+	unit, ok := timestampUnit(format)
+	if !ok {
+		t, err := time.Parse(format, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to parse timestamp %q: %s", value, err)
+		}
+		return t, nil
+	}
+
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, v*int64(unit)).UTC(), nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s timestamp %q", format, value)
+	}
+	return ParseUnixTimestamp(v, format)
+}
+
+// ParseUnixTimestamp returns the time of a number of units since the epoch,
+// format being "unix", "unix_ms", "unix_us" or "unix_ns".
+func ParseUnixTimestamp(v float64, format string) (time.Time, error) {
+	unit, ok := timestampUnit(format)
+	if !ok {
+		return time.Time{}, fmt.Errorf("numeric timestamp with layout %q, expected a unix format", format)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, fmt.Errorf("invalid %s timestamp %v", format, v)
+	}
+	// the integer part is converted separately, as float64 is not precise
+	// enough for nanoseconds.
+	i, frac := math.Modf(v)
+	return time.Unix(0, int64(i)*int64(unit)+int64(frac*float64(unit))).UTC(), nil
+}
+
+// timestampUnit returns the unit of a unix timestamp format.
+func timestampUnit(format string) (time.Duration, bool) {
+	switch format {
+	case "unix":
+		return time.Second, true
+	case "unix_ms":
+		return time.Millisecond, true
+	case "unix_us":
+		return time.Microsecond, true
+	case "unix_ns":
+		return time.Nanosecond, true
+	default:
+		return 0, false
+	}
+}
+
+// InferValue returns the value as an integer, float or boolean when possible, or
+// as a string. Only decimal numbers are inferred, values such as "nan",
+// "Inf" or "0x1p-2" stay strings.
+func InferValue(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if isDecimal(value) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
+
+// isDecimal returns whether value only has the characters of decimal
+// numbers, as strconv.ParseFloat also accepts hexadecimal numbers,
+// infinities and NaN.
+func isDecimal(value string) bool {
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' || c == '-' || c == '+' || c == 'e' || c == 'E':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // CombinedOutputTimeout runs the given command with the given timeout and
 // returns the combined output of stdout and stderr.
 // If the command times out, it attempts to kill the process.
@@ -155,3 +155,66 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestLookupPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{"value": 1.0},
+		},
+	}
+
+	v, err := LookupPath(doc, "data.0.value")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, v)
+
+	_, err = LookupPath(doc, "data.1")
+	assert.EqualError(t, err, `invalid index "1"`)
+	_, err = LookupPath(doc, "data.0.missing")
+	assert.EqualError(t, err, `"missing" not found`)
+	_, err = LookupPath(doc, "data.0.value.x")
+	assert.EqualError(t, err, `"x" not found`)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("1458229140", "unix")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1458229140, 0).UTC(), ts)
+
+	ts, err = ParseTimestamp("1458229140.5", "unix")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1458229140, 500000000).UTC(), ts)
+
+	ts, err = ParseTimestamp("1458229140000000001", "unix_ns")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1458229140, 1).UTC(), ts)
+
+	ts, err = ParseTimestamp("2016-03-17T15:39:00Z", time.RFC3339)
+	assert.NoError(t, err)
+	assert.True(t, time.Unix(1458229140, 0).Equal(ts))
+
+	_, err = ParseTimestamp("NaN", "unix_ms")
+	assert.Error(t, err)
+	_, err = ParseTimestamp("soon", "unix")
+	assert.EqualError(t, err, `invalid unix timestamp "soon"`)
+	_, err = ParseTimestamp("soon", time.RFC3339)
+	assert.Error(t, err)
+}
+
+func TestInferValue(t *testing.T) {
+	assert.Equal(t, int64(-2), InferValue("-2"))
+	assert.Equal(t, 1500.0, InferValue("1.5e3"))
+	assert.Equal(t, 0.5, InferValue(".5"))
+	assert.Equal(t, true, InferValue("true"))
+	for _, v := range []string{"nan", "Inf", "0x10", "0x1p-2", "t", "F", "TRUE"} {
+		assert.Equal(t, v, InferValue(v))
+	}
+}
+
+func TestParseUnixTimestamp(t *testing.T) {
+	ts, err := ParseUnixTimestamp(1458229140500, "unix_ms")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1458229140, 500000000).UTC(), ts)
+
+	_, err = ParseUnixTimestamp(1458229140, time.RFC3339)
+	assert.Error(t, err)
+}
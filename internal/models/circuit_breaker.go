@@ -0,0 +1,149 @@
+package models
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// States of the circuit breaker, as reported by the circuit_state stat.
+const (
+	circuitClosed int64 = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// RetryConfig is the retry policy of an output.
+type RetryConfig struct {
+	// InitialDelay is the time writes are suspended for once the circuit
+	// opens. It doubles every time a trial write fails, up to MaxDelay.
+	// Failed writes are retried on every flush when it is zero.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// Jitter is the maximum random time added to each delay.
+	Jitter time.Duration
+	// MaxAttempts is the number of times the oldest batch is written before
+	// it is dropped, it is never dropped when zero.
+	MaxAttempts int
+	// CircuitBreakerThreshold is the number of consecutive failed writes
+	// which opens the circuit.
+	CircuitBreakerThreshold int
+}
+
+// circuitBreaker suspends the writes to an output after consecutive
+// failures. While the circuit is open writes are skipped; once the delay has
+// elapsed a single trial write is let through, which either closes the
+// circuit or opens it again for twice as long.
+type circuitBreaker struct {
+	name string
+	conf RetryConfig
+
+	mu       sync.Mutex
+	state    int64
+	failures int
+	// attempts is the number of failed writes of the oldest batch.
+	attempts int
+	// delay is the current time the circuit stays open.
+	delay       time.Duration
+	nextAttempt time.Time
+
+	stateStat selfstat.Stat
+	opened    selfstat.Stat
+}
+
+func newCircuitBreaker(name string, conf RetryConfig) *circuitBreaker {
+	if conf.CircuitBreakerThreshold <= 0 {
+		conf.CircuitBreakerThreshold = 1
+	}
+	if conf.MaxDelay < conf.InitialDelay {
+		conf.MaxDelay = conf.InitialDelay
+	}
+	tags := map[string]string{"output": name}
+	cb := &circuitBreaker{
+		name:      name,
+		conf:      conf,
+		stateStat: selfstat.Register("write", "circuit_state", tags),
+		opened:    selfstat.Register("write", "circuit_opened", tags),
+	}
+	cb.stateStat.Set(circuitClosed)
+	return cb
+}
+
+// allow returns true if a write may be attempted at now.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if now.Before(cb.nextAttempt) {
+			return false
+		}
+		log.Printf("D! Output [%s] circuit half-open, trying a write", cb.name)
+		cb.setState(circuitHalfOpen)
+	case circuitHalfOpen:
+		// only the trial write is let through
+		return false
+	}
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != circuitClosed {
+		log.Printf("I! Output [%s] circuit closed, writes resumed", cb.name)
+	}
+	cb.failures = 0
+	cb.attempts = 0
+	cb.delay = 0
+	cb.setState(circuitClosed)
+}
+
+// failure records a failed write at now, and returns true when the batch
+// that failed has been attempted too many times and must be dropped.
+func (cb *circuitBreaker) failure(now time.Time) (drop bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.attempts++
+	if cb.conf.MaxAttempts > 0 && cb.attempts >= cb.conf.MaxAttempts {
+		cb.attempts = 0
+		drop = true
+	}
+
+	if cb.conf.InitialDelay <= 0 || cb.failures < cb.conf.CircuitBreakerThreshold {
+		return drop
+	}
+
+	switch {
+	case cb.delay == 0:
+		cb.delay = cb.conf.InitialDelay
+	case cb.delay < cb.conf.MaxDelay:
+		cb.delay *= 2
+		if cb.delay > cb.conf.MaxDelay {
+			cb.delay = cb.conf.MaxDelay
+		}
+	}
+	delay := cb.delay
+	if cb.conf.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(cb.conf.Jitter.Nanoseconds()))
+	}
+	cb.nextAttempt = now.Add(delay)
+
+	log.Printf("W! Output [%s] circuit open after %d failed writes, "+
+		"next attempt in %s", cb.name, cb.failures, delay)
+	cb.opened.Incr(1)
+	cb.setState(circuitOpen)
+	return drop
+}
+
+func (cb *circuitBreaker) setState(state int64) {
+	cb.state = state
+	cb.stateStat.Set(state)
+}
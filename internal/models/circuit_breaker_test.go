@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := newCircuitBreaker("test", RetryConfig{})
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, cb.allow(now))
+		assert.False(t, cb.failure(now))
+	}
+	assert.Equal(t, circuitClosed, cb.stateStat.Get())
+}
+
+func TestCircuitBreakerBackoff(t *testing.T) {
+	cb := newCircuitBreaker("test", RetryConfig{
+		InitialDelay:            time.Second,
+		MaxDelay:                3 * time.Second,
+		CircuitBreakerThreshold: 2,
+	})
+	opened := cb.opened.Get()
+	now := time.Now()
+
+	// the first failure is below the threshold
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.Equal(t, circuitOpen, cb.stateStat.Get())
+	assert.Equal(t, opened+1, cb.opened.Get())
+
+	assert.False(t, cb.allow(now.Add(999*time.Millisecond)))
+
+	// a single trial write is let through once the delay elapsed
+	now = now.Add(time.Second)
+	assert.True(t, cb.allow(now))
+	assert.Equal(t, circuitHalfOpen, cb.stateStat.Get())
+	assert.False(t, cb.allow(now))
+
+	// and the delay doubles when it fails, up to the maximum
+	cb.failure(now)
+	assert.False(t, cb.allow(now.Add(time.Second)))
+	now = now.Add(2 * time.Second)
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.False(t, cb.allow(now.Add(2*time.Second)))
+	now = now.Add(3 * time.Second)
+	assert.True(t, cb.allow(now))
+
+	cb.success()
+	assert.Equal(t, circuitClosed, cb.stateStat.Get())
+	assert.True(t, cb.allow(now))
+	assert.True(t, cb.allow(now))
+
+	// the backoff starts over
+	cb.failure(now)
+	cb.failure(now)
+	assert.True(t, cb.allow(now.Add(time.Second)))
+}
+
+func TestCircuitBreakerJitter(t *testing.T) {
+	cb := newCircuitBreaker("test", RetryConfig{
+		InitialDelay: time.Second,
+		Jitter:       time.Second,
+	})
+	now := time.Now()
+
+	cb.failure(now)
+	assert.False(t, cb.allow(now.Add(999*time.Millisecond)))
+	assert.True(t, cb.allow(now.Add(2*time.Second)))
+}
+
+func TestCircuitBreakerMaxAttempts(t *testing.T) {
+	cb := newCircuitBreaker("test", RetryConfig{MaxAttempts: 3})
+	now := time.Now()
+
+	assert.False(t, cb.failure(now))
+	assert.False(t, cb.failure(now))
+	assert.True(t, cb.failure(now))
+
+	// the next batch gets as many attempts
+	assert.False(t, cb.failure(now))
+	cb.success()
+	assert.False(t, cb.failure(now))
+	assert.False(t, cb.failure(now))
+	assert.True(t, cb.failure(now))
+}
@@ -1,18 +1,35 @@
 package models
 
 import (
+	"log"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
+// defaultGraceMetricLimit is the number of metrics held by an open period
+// when GraceMetricLimit is not set.
+const defaultGraceMetricLimit = 10000
+
 type RunningAggregator struct {
 	a      telegraf.Aggregator
 	Config *AggregatorConfig
 
 	metrics chan telegraf.Metric
 
+	// MetricsDroppedLate counts the metrics dropped as they are older than
+	// the open periods, MetricsDroppedEarly the metrics newer than the
+	// current period, and MetricsDroppedOverflow the metrics dropped as
+	// their open period holds GraceMetricLimit metrics.
+	MetricsDroppedLate     selfstat.Stat
+	MetricsDroppedEarly    selfstat.Stat
+	MetricsDroppedOverflow selfstat.Stat
+
+	// mu guards the period, which is reported by the agent API.
+	mu          sync.Mutex
 	periodStart time.Time
 	periodEnd   time.Time
 }
@@ -25,6 +42,21 @@ func NewRunningAggregator(
 		a:       a,
 		Config:  conf,
 		metrics: make(chan telegraf.Metric, 100),
+		MetricsDroppedLate: selfstat.Register(
+			"aggregate",
+			"metrics_dropped_late",
+			map[string]string{"aggregator": conf.Name},
+		),
+		MetricsDroppedEarly: selfstat.Register(
+			"aggregate",
+			"metrics_dropped_early",
+			map[string]string{"aggregator": conf.Name},
+		),
+		MetricsDroppedOverflow: selfstat.Register(
+			"aggregate",
+			"metrics_dropped_overflow",
+			map[string]string{"aggregator": conf.Name},
+		),
 	}
 }
 
@@ -42,6 +74,17 @@ type AggregatorConfig struct {
 
 	Period time.Duration
 	Delay  time.Duration
+
+	// GracePeriods is the number of periods during which a period is kept
+	// open for late metrics once it has ended. The aggregates of a period
+	// are pushed when it is closed.
+	GracePeriods int
+	// GraceMetricLimit is the maximum number of metrics held by an open
+	// period, defaultGraceMetricLimit when not set.
+	GraceMetricLimit int
+	// UseArrivalTime aggregates the metrics in the period they are received
+	// in, instead of the period of their timestamp.
+	UseArrivalTime bool
 }
 
 func (r *RunningAggregator) Name() string {
@@ -87,7 +130,10 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 		fields := in.Fields()
 		tags := in.Tags()
 		t := in.Time()
-		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
+		ok := r.Config.Filter.Apply(name, fields, tags)
+		// the aggregator never holds on to the metric it was given
+		in.Drop()
+		if !ok {
 			// aggregator should not apply this metric
 			return false
 		}
@@ -98,6 +144,21 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 	r.metrics <- in
 	return r.Config.DropOriginal
 }
+
+// Period returns the boundaries of the current aggregation period.
+func (r *RunningAggregator) Period() (start, end time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.periodStart, r.periodEnd
+}
+
+func (r *RunningAggregator) setPeriod(start time.Time) {
+	r.mu.Lock()
+	r.periodStart = start
+	r.periodEnd = start.Add(r.Config.Period)
+	r.mu.Unlock()
+}
+
 func (r *RunningAggregator) add(in telegraf.Metric) {
 	r.a.Add(in)
 }
@@ -110,6 +171,16 @@ func (r *RunningAggregator) reset() {
 	r.a.Reset()
 }
 
+// openPeriod holds the metrics of a period kept open for late metrics,
+// which are added to the aggregator when the period is closed.
+type openPeriod struct {
+	start   time.Time
+	end     time.Time
+	metrics []telegraf.Metric
+	// overflowed is set once a metric was dropped as the period is full.
+	overflowed bool
+}
+
 // Run runs the running aggregator, listens for incoming metrics, and waits
 // for period ticks to tell it when to push and reset the aggregator.
 func (r *RunningAggregator) Run(
@@ -133,13 +204,26 @@ func (r *RunningAggregator) Run(
 	// 2nd interval: 00:10 - 00:20.5
 	// etc.
 	//
-	r.periodStart = now.Truncate(time.Second)
+	// With grace periods, the previous periods are kept open, and metrics are
+	// held in the latest open period starting before them.
+	//
+	r.setPeriod(now.Truncate(time.Second))
 	truncation := now.Sub(r.periodStart)
-	r.periodEnd = r.periodStart.Add(r.Config.Period)
 	time.Sleep(r.Config.Delay)
 	periodT := time.NewTicker(r.Config.Period)
 	defer periodT.Stop()
 
+	// open holds the open periods when there is a grace, the last one being
+	// the current period.
+	var open []*openPeriod
+	if r.Config.GracePeriods > 0 {
+		open = append(open, &openPeriod{start: r.periodStart, end: r.periodEnd})
+	}
+	limit := r.Config.GraceMetricLimit
+	if limit <= 0 {
+		limit = defaultGraceMetricLimit
+	}
+
 	for {
 		select {
 		case <-shutdown:
@@ -147,20 +231,153 @@ func (r *RunningAggregator) Run(
 				// wait until metrics are flushed before exiting
 				continue
 			}
+			// the metrics held by the open periods are aggregated and
+			// pushed rather than lost
+			for _, p := range open {
+				r.close(acc, p)
+			}
 			return
 		case m := <-r.metrics:
-			if m.Time().Before(r.periodStart) ||
-				m.Time().After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
-				// the metric is outside the current aggregation period, so
+			t := m.Time()
+			if r.Config.UseArrivalTime {
+				t = time.Now()
+			}
+			if t.After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
+				// the metric is after the current aggregation period, so
 				// skip it.
+				r.MetricsDroppedEarly.Incr(1)
+				m.Drop()
 				continue
 			}
-			r.add(m)
+
+			if open == nil {
+				if t.Before(r.periodStart) {
+					// the metric is before the current aggregation
+					// period, so skip it.
+					r.MetricsDroppedLate.Incr(1)
+					m.Drop()
+					continue
+				}
+				r.add(m)
+				m.Drop()
+				continue
+			}
+
+			p := findOpenPeriod(open, t)
+			if p == nil {
+				r.MetricsDroppedLate.Incr(1)
+				m.Drop()
+				continue
+			}
+			if len(p.metrics) >= limit {
+				if !p.overflowed {
+					p.overflowed = true
+					log.Printf("W! Aggregator [%s] holds %d metrics for the "+
+						"period starting at %s, dropping the metrics added "+
+						"to it until it is closed; consider raising "+
+						"grace_metric_limit", r.Config.Name, limit,
+						p.start.Format(time.RFC3339))
+				}
+				r.MetricsDroppedOverflow.Incr(1)
+				m.Drop()
+				continue
+			}
+			p.metrics = append(p.metrics, m)
 		case <-periodT.C:
-			r.periodStart = r.periodEnd
-			r.periodEnd = r.periodStart.Add(r.Config.Period)
-			r.push(acc)
-			r.reset()
+			r.setPeriod(r.periodEnd)
+			if open == nil {
+				r.push(acc)
+				r.reset()
+				continue
+			}
+
+			open = append(open, &openPeriod{start: r.periodStart, end: r.periodEnd})
+			if len(open) > r.Config.GracePeriods+1 {
+				r.close(acc, open[0])
+				open[0] = nil
+				open = open[1:]
+			}
+		}
+	}
+}
+
+// findOpenPeriod returns the latest open period starting before t, or nil
+// when t is before all of them.
+func findOpenPeriod(open []*openPeriod, t time.Time) *openPeriod {
+	for i := len(open) - 1; i >= 0; i-- {
+		if !t.Before(open[i].start) {
+			return open[i]
 		}
 	}
+	return nil
+}
+
+// close adds the metrics of a period to the aggregator, and pushes its
+// aggregates stamped with the end of the period.
+func (r *RunningAggregator) close(acc telegraf.Accumulator, p *openPeriod) {
+	for _, m := range p.metrics {
+		r.add(m)
+		m.Drop()
+	}
+	r.push(&periodAccumulator{Accumulator: acc, end: p.end})
+	r.reset()
+}
+
+// periodAccumulator stamps the metrics without timestamp with the end of
+// their period, rather than with the time they are pushed at.
+type periodAccumulator struct {
+	telegraf.Accumulator
+	end time.Time
+}
+
+func (a *periodAccumulator) time(t []time.Time) []time.Time {
+	if len(t) > 0 {
+		return t
+	}
+	return []time.Time{a.end}
+}
+
+func (a *periodAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddFields(measurement, fields, tags, a.time(t)...)
+}
+
+func (a *periodAccumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddGauge(measurement, fields, tags, a.time(t)...)
+}
+
+func (a *periodAccumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddCounter(measurement, fields, tags, a.time(t)...)
+}
+
+func (a *periodAccumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddSummary(measurement, fields, tags, a.time(t)...)
+}
+
+func (a *periodAccumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddHistogram(measurement, fields, tags, a.time(t)...)
 }
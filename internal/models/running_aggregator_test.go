@@ -96,6 +96,107 @@ func TestAddMetricsOutsideCurrentPeriod(t *testing.T) {
 	assert.Equal(t, int64(101), atomic.LoadInt64(&a.sum))
 }
 
+func TestAddMetricsOutsideCurrentPeriodCounted(t *testing.T) {
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:   "TestDroppedCounted",
+		Period: time.Millisecond * 500,
+	})
+	droppedLate := ra.MetricsDroppedLate.Get()
+	droppedEarly := ra.MetricsDroppedEarly.Get()
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go ra.Run(&acc, time.Now(), shutdown)
+
+	late := ra.MakeMetric("RITest", map[string]interface{}{"value": int(1)},
+		map[string]string{}, telegraf.Untyped, time.Now().Add(-time.Hour))
+	early := ra.MakeMetric("RITest", map[string]interface{}{"value": int(1)},
+		map[string]string{}, telegraf.Untyped, time.Now().Add(time.Hour))
+	ra.Add(late)
+	ra.Add(early)
+
+	for ra.MetricsDroppedLate.Get() == droppedLate ||
+		ra.MetricsDroppedEarly.Get() == droppedEarly {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, droppedLate+1, ra.MetricsDroppedLate.Get())
+	assert.Equal(t, droppedEarly+1, ra.MetricsDroppedEarly.Get())
+}
+
+func TestAddWithArrivalTime(t *testing.T) {
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:           "TestRunningAggregator",
+		Period:         time.Millisecond * 500,
+		UseArrivalTime: true,
+	})
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go ra.Run(&acc, time.Now(), shutdown)
+
+	// the metric is aggregated in the period it is received in
+	m := ra.MakeMetric("RITest", map[string]interface{}{"value": int(101)},
+		map[string]string{}, telegraf.Untyped, time.Now().Add(-time.Hour))
+	assert.False(t, ra.Add(m))
+
+	for atomic.LoadInt64(&a.sum) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int64(101), atomic.LoadInt64(&a.sum))
+}
+
+func TestAddLateMetricsWithGrace(t *testing.T) {
+	a := &TestAggregator{}
+	period := time.Millisecond * 200
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:         "TestGrace",
+		Period:       period,
+		GracePeriods: 1,
+	})
+	droppedLate := ra.MetricsDroppedLate.Get()
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go ra.Run(&acc, time.Now(), shutdown)
+
+	var start time.Time
+	for start.IsZero() {
+		time.Sleep(time.Millisecond)
+		start, _ = ra.Period()
+	}
+
+	m := ra.MakeMetric("RITest", map[string]interface{}{"value": int(1)},
+		map[string]string{}, telegraf.Untyped, start)
+	ra.Add(m)
+
+	// once the first period has ended, it is still open for late metrics
+	for {
+		time.Sleep(time.Millisecond)
+		if current, _ := ra.Period(); current != start {
+			break
+		}
+	}
+	m = ra.MakeMetric("RITest", map[string]interface{}{"value": int(2)},
+		map[string]string{}, telegraf.Untyped, start)
+	ra.Add(m)
+	m = ra.MakeMetric("RITest", map[string]interface{}{"value": int(4)},
+		map[string]string{}, telegraf.Untyped, start.Add(-time.Hour))
+	ra.Add(m)
+
+	// the first period is pushed when it is closed, stamped with its end
+	for acc.NMetrics() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	acc.Lock()
+	pushed := *acc.Metrics[0]
+	acc.Unlock()
+	assert.Equal(t, map[string]interface{}{"sum": int64(3)}, pushed.Fields)
+	assert.True(t, start.Add(period).Equal(pushed.Time))
+	assert.Equal(t, droppedLate+1, ra.MetricsDroppedLate.Get())
+}
+
 func TestAddAndPushOnePeriod(t *testing.T) {
 	a := &TestAggregator{}
 	ra := NewRunningAggregator(a, &AggregatorConfig{
@@ -254,3 +355,78 @@ func (t *TestAggregator) Add(in telegraf.Metric) {
 		}
 	}
 }
+
+func TestShutdownClosesOpenPeriods(t *testing.T) {
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:         "TestShutdownGrace",
+		Period:       time.Hour,
+		GracePeriods: 1,
+	})
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ra.Run(&acc, time.Now(), shutdown)
+	}()
+
+	var start time.Time
+	for start.IsZero() {
+		time.Sleep(time.Millisecond)
+		start, _ = ra.Period()
+	}
+	m := ra.MakeMetric("RITest", map[string]interface{}{"value": int(5)},
+		map[string]string{}, telegraf.Untyped, start)
+	ra.Add(m)
+	for len(ra.metrics) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the metrics held by the open period are pushed on shutdown
+	close(shutdown)
+	wg.Wait()
+	assert.Equal(t, uint64(1), acc.NMetrics())
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(5)})
+}
+
+func TestGraceMetricLimit(t *testing.T) {
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:             "TestGraceLimit",
+		Period:           time.Hour,
+		GracePeriods:     1,
+		GraceMetricLimit: 2,
+	})
+	droppedOverflow := ra.MetricsDroppedOverflow.Get()
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ra.Run(&acc, time.Now(), shutdown)
+	}()
+
+	var start time.Time
+	for start.IsZero() {
+		time.Sleep(time.Millisecond)
+		start, _ = ra.Period()
+	}
+	for _, v := range []int{1, 2, 4} {
+		m := ra.MakeMetric("RITest", map[string]interface{}{"value": v},
+			map[string]string{}, telegraf.Untyped, start)
+		ra.Add(m)
+	}
+	for ra.MetricsDroppedOverflow.Get() == droppedOverflow {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, droppedOverflow+1, ra.MetricsDroppedOverflow.Get())
+
+	close(shutdown)
+	wg.Wait()
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(3)})
+}
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -18,6 +20,18 @@ type RunningInput struct {
 	defaultTags map[string]string
 
 	MetricsGathered selfstat.Stat
+	GatherTime      selfstat.Stat
+
+	mu     sync.Mutex
+	status InputStatus
+}
+
+// InputStatus is the state of a RunningInput, as reported by the agent API.
+type InputStatus struct {
+	LastGather         time.Time
+	LastGatherDuration time.Duration
+	LastError          string
+	LastErrorTime      time.Time
 }
 
 func NewRunningInput(
@@ -32,6 +46,11 @@ func NewRunningInput(
 			"metrics_gathered",
 			map[string]string{"input": config.Name},
 		),
+		GatherTime: selfstat.RegisterTiming(
+			"gather",
+			"gather_time_ns",
+			map[string]string{"input": config.Name},
+		),
 	}
 }
 
@@ -75,7 +94,7 @@ func (r *RunningInput) MakeMetric(
 	)
 
 	if r.trace && m != nil {
-		fmt.Print("> " + m.String())
+		fmt.Print("> " + secret.Redact(m.String()))
 	}
 
 	r.MetricsGathered.Incr(1)
@@ -94,3 +113,29 @@ func (r *RunningInput) SetTrace(trace bool) {
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
+
+// GatherDone records the completion of a Gather which started at start.
+func (r *RunningInput) GatherDone(start time.Time) {
+	elapsed := time.Since(start)
+	r.GatherTime.Incr(elapsed.Nanoseconds())
+
+	r.mu.Lock()
+	r.status.LastGather = start
+	r.status.LastGatherDuration = elapsed
+	r.mu.Unlock()
+}
+
+// SetError records the last error reported by the input.
+func (r *RunningInput) SetError(err error) {
+	r.mu.Lock()
+	r.status.LastError = err.Error()
+	r.status.LastErrorTime = time.Now()
+	r.mu.Unlock()
+}
+
+// Status returns the current state of the input.
+func (r *RunningInput) Status() InputStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -19,6 +20,10 @@ const (
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
 )
 
+// errCircuitOpen is returned by write while the circuit of the output is
+// open.
+var errCircuitOpen = errors.New("circuit open")
+
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	Name              string
@@ -32,14 +37,52 @@ type RunningOutput struct {
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	MetricsDropped  selfstat.Stat
+	// BufferBytes is only registered when the output uses a disk buffer.
+	BufferBytes selfstat.Stat
+
+	// BatchReady is signaled when a full batch of metrics is waiting to be
+	// written, so that it is written before the next flush.
+	BatchReady chan struct{}
+
+	// metrics holds the metrics added since the last Write. AddMetric only
+	// appends to it, the metrics are only taken out of it by Write.
+	metrics *buffer.Buffer
+	// failMetrics holds the metrics which failed to be written, in the order
+	// they are written. It is only used by Write.
+	failMetrics buffer.MetricBuffer
+	// disk is the disk buffer of the output, if it has one, in which case
+	// it is also failMetrics, and all metrics are added directly to it.
+	disk *buffer.DiskBuffer
+	// pending is the number of metrics added to the disk buffer since the
+	// last full batch.
+	pending int
 
-	metrics     *buffer.Buffer
-	failMetrics *buffer.Buffer
+	breaker *circuitBreaker
+
+	statusMu sync.Mutex
+	status   OutputStatus
 
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
 }
 
+// OutputStatus is the state of a RunningOutput, as reported by the agent API.
+type OutputStatus struct {
+	// BufferSize is the number of metrics waiting to be written.
+	BufferSize  int
+	BufferLimit int
+
+	LastWrite         time.Time
+	LastWriteDuration time.Duration
+	LastError         string
+	LastErrorTime     time.Time
+	// FailingSince is the time of the first of the writes that have failed
+	// since the last successful one, it is zero when the last write
+	// succeeded.
+	FailingSince time.Time
+}
+
 func NewRunningOutput(
 	name string,
 	output telegraf.Output,
@@ -55,7 +98,7 @@ func NewRunningOutput(
 	}
 	ro := &RunningOutput{
 		Name:              name,
-		metrics:           buffer.NewBuffer(batchSize),
+		metrics:           buffer.NewBuffer(bufferLimit),
 		failMetrics:       buffer.NewBuffer(bufferLimit),
 		Output:            output,
 		Config:            conf,
@@ -86,13 +129,39 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			map[string]string{"output": name},
+		),
+		BatchReady: make(chan struct{}, 1),
+		breaker:    newCircuitBreaker(name, conf.Retry),
+	}
+	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
+
+	if conf.BufferPath != "" {
+		db, err := buffer.NewDiskBuffer(conf.BufferPath, conf.BufferMaxSize,
+			conf.BufferSegmentSize, conf.BufferFsync)
+		if err != nil {
+			log.Printf("E! Unable to open disk buffer for output [%s], "+
+				"falling back to an in-memory buffer: %s", name, err)
+		} else {
+			ro.failMetrics = db
+			ro.disk = db
+			ro.BufferBytes = selfstat.Register(
+				"write",
+				"buffer_bytes",
+				map[string]string{"output": name},
+			)
+			ro.BufferBytes.Set(db.Size())
+			ro.BufferSize.Set(int64(db.Len()))
+		}
 	}
-	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
 	return ro
 }
 
-// AddMetric adds a metric to the output. This function can also write cached
-// points if FlushBufferWhenFull is true.
+// AddMetric adds a metric to the output. It never writes to the output, but
+// signals BatchReady once a full batch is waiting to be written.
 func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	if m == nil {
 		return
@@ -107,24 +176,47 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 		t := m.Time()
 		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
 		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		filtered, _ := metric.New(name, tags, fields, t)
+		m = metric.WithTrackingFrom(m, filtered)
 	}
 
-	ro.metrics.Add(m)
-	if ro.metrics.Len() == ro.MetricBatchSize {
-		batch := ro.metrics.Batch(ro.MetricBatchSize)
-		err := ro.write(batch)
-		if err != nil {
-			ro.failMetrics.Add(batch...)
+	if ro.disk != nil {
+		// metrics are written to disk straight away, and are sent to the
+		// output on the next Write.
+		ro.failMetrics.Add(m)
+		ro.pending++
+		if ro.pending >= ro.MetricBatchSize {
+			ro.pending = 0
+			ro.batchReady()
 		}
+		return
+	}
+
+	ro.metrics.Add(m)
+	if ro.metrics.Len() >= ro.MetricBatchSize {
+		// the batch is left to the flush loop of the output, writing it here
+		// would hold up the other outputs.
+		ro.batchReady()
+	}
+}
+
+func (ro *RunningOutput) batchReady() {
+	select {
+	case ro.BatchReady <- struct{}{}:
+	default:
 	}
 }
 
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
+	if ro.disk != nil {
+		return ro.writeDisk()
+	}
+
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
@@ -146,28 +238,107 @@ func (ro *RunningOutput) Write() error {
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
-			}
-			if err != nil {
+				err = ro.writeBatch(batch)
+			} else {
 				ro.failMetrics.Add(batch...)
 			}
 		}
 	}
 
-	batch := ro.metrics.Batch(ro.MetricBatchSize)
-	// see comment above about not trying to write to an already failed output.
-	// if ro.failMetrics is empty then err will always be nil at this point.
-	if err == nil {
+	// only the metrics added before the write started are written, the
+	// others are left to the next one.
+	for n := nMetrics; n > 0; n -= ro.MetricBatchSize {
+		batchSize := ro.MetricBatchSize
+		if n < batchSize {
+			batchSize = n
+		}
+		batch := ro.metrics.Batch(batchSize)
+		// see comment above about not trying to write to an already failed
+		// output. if ro.failMetrics is empty then err will always be nil at
+		// this point.
+		if err == nil {
+			err = ro.writeBatch(batch)
+		} else {
+			ro.failMetrics.Add(batch...)
+		}
+	}
+
+	if err == errCircuitOpen {
+		log.Printf("D! Output [%s] circuit open, skipping write", ro.Name)
+		return nil
+	}
+	return err
+}
+
+// writeDisk writes the metrics of the disk buffer to the output. Each batch
+// is only removed from the disk buffer once it has been written, or dropped
+// after too many failed attempts, so that failed batches stay in place.
+func (ro *RunningOutput) writeDisk() error {
+	n := ro.disk.Len()
+	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
+		ro.Name, n, ro.MetricBufferLimit)
+
+	var err error
+	for i := 0; i < n; i += ro.MetricBatchSize {
+		batch := ro.disk.Peek(ro.MetricBatchSize)
+		if len(batch) == 0 {
+			break
+		}
 		err = ro.write(batch)
+		if err == nil {
+			ro.disk.Commit()
+			continue
+		}
+		if err != errCircuitOpen && ro.breaker.failure(time.Now()) {
+			log.Printf("W! Output [%s] dropping batch of %d metrics after %d "+
+				"failed attempts", ro.Name, len(batch), ro.Config.Retry.MaxAttempts)
+			ro.MetricsDropped.Incr(int64(len(batch)))
+			ro.disk.Commit()
+		}
+		break
 	}
 
-	if err != nil {
-		ro.failMetrics.Add(batch...)
-		return err
+	ro.BufferSize.Set(int64(ro.disk.Len()))
+	ro.BufferBytes.Set(ro.disk.Size())
+	if err == errCircuitOpen {
+		log.Printf("D! Output [%s] circuit open, skipping write", ro.Name)
+		return nil
+	}
+	return err
+}
+
+// Close closes the output's disk buffer, if it has one.
+func (ro *RunningOutput) Close() error {
+	if ro.disk != nil {
+		return ro.disk.Close()
 	}
 	return nil
 }
 
+// writeBatch writes a batch to the output. When the write fails the batch is
+// kept to be retried, unless it has been attempted too many times.
+func (ro *RunningOutput) writeBatch(batch []telegraf.Metric) error {
+	err := ro.write(batch)
+	if err == nil || err == errCircuitOpen {
+		if err != nil {
+			ro.failMetrics.Add(batch...)
+		}
+		return err
+	}
+
+	if ro.breaker.failure(time.Now()) {
+		log.Printf("W! Output [%s] dropping batch of %d metrics after %d "+
+			"failed attempts", ro.Name, len(batch), ro.Config.Retry.MaxAttempts)
+		ro.MetricsDropped.Incr(int64(len(batch)))
+		for _, m := range batch {
+			m.Reject()
+		}
+		return err
+	}
+	ro.failMetrics.Add(batch...)
+	return err
+}
+
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	nMetrics := len(metrics)
 	if nMetrics == 0 {
@@ -176,19 +347,72 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	ro.Lock()
 	defer ro.Unlock()
 	start := time.Now()
+	if !ro.breaker.allow(start) {
+		return errCircuitOpen
+	}
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
+	ro.setWriteStatus(start, elapsed, err)
 	if err == nil {
+		ro.breaker.success()
 		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
 			ro.Name, nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		for _, m := range metrics {
+			m.Accept()
+		}
 	}
 	return err
 }
 
+func (ro *RunningOutput) setWriteStatus(start time.Time, elapsed time.Duration, err error) {
+	ro.statusMu.Lock()
+	defer ro.statusMu.Unlock()
+	ro.status.LastWrite = start
+	ro.status.LastWriteDuration = elapsed
+	if err == nil {
+		ro.status.FailingSince = time.Time{}
+		return
+	}
+	ro.status.LastError = err.Error()
+	ro.status.LastErrorTime = start
+	if ro.status.FailingSince.IsZero() {
+		ro.status.FailingSince = start
+	}
+}
+
+// Status returns the current state of the output.
+func (ro *RunningOutput) Status() OutputStatus {
+	ro.statusMu.Lock()
+	status := ro.status
+	ro.statusMu.Unlock()
+
+	status.BufferSize = ro.metrics.Len() + ro.failMetrics.Len()
+	status.BufferLimit = ro.MetricBufferLimit
+	return status
+}
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// BufferPath is the directory of the disk buffer. When empty, metrics
+	// are buffered in memory.
+	BufferPath        string
+	BufferMaxSize     int64
+	BufferSegmentSize int64
+	BufferFsync       string
+
+	// FlushInterval and FlushJitter override the flush_interval and
+	// flush_jitter of the agent when they are not zero.
+	FlushInterval time.Duration
+	FlushJitter   time.Duration
+	// MetricBatchSize and MetricBufferLimit override the metric_batch_size
+	// and metric_buffer_limit of the agent when they are not zero.
+	MetricBatchSize   int
+	MetricBufferLimit int
+
+	Retry RetryConfig
 }
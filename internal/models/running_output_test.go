@@ -2,10 +2,14 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -248,8 +252,8 @@ func TestRunningOutputDefault(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
-// Test that running output doesn't flush until it's full when
-// FlushBufferWhenFull is set.
+// Test that running output signals that a batch is ready once it's full,
+// without writing it.
 func TestRunningOutputFlushWhenFull(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -262,23 +266,24 @@ func TestRunningOutputFlushWhenFull(t *testing.T) {
 	for _, metric := range first5 {
 		ro.AddMetric(metric)
 	}
-	// no flush yet
-	assert.Len(t, m.Metrics(), 0)
+	// no batch yet
+	assert.Len(t, ro.BatchReady, 0)
 
 	// add one more metric
 	ro.AddMetric(next5[0])
-	// now it flushed
-	assert.Len(t, m.Metrics(), 6)
+	// now a batch is ready, but is only written by Write
+	assert.Len(t, ro.BatchReady, 1)
+	assert.Len(t, m.Metrics(), 0)
 
-	// add one more metric and write it manually
+	// add one more metric and write them all
 	ro.AddMetric(next5[1])
 	err := ro.Write()
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 7)
+	assert.Equal(t, append(first5, next5[:2]...), m.Metrics())
 }
 
-// Test that running output doesn't flush until it's full when
-// FlushBufferWhenFull is set, twice.
+// Test that running output signals a full batch twice.
 func TestRunningOutputMultiFlushWhenFull(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -287,15 +292,20 @@ func TestRunningOutputMultiFlushWhenFull(t *testing.T) {
 	m := &mockOutput{}
 	ro := NewRunningOutput("test", m, conf, 4, 12)
 
-	// Fill buffer past limit twive
+	// Fill buffer past limit twice
 	for _, metric := range first5 {
 		ro.AddMetric(metric)
 	}
+	<-ro.BatchReady
 	for _, metric := range next5 {
 		ro.AddMetric(metric)
 	}
-	// flushed twice
-	assert.Len(t, m.Metrics(), 8)
+	assert.Len(t, ro.BatchReady, 1)
+	assert.Len(t, m.Metrics(), 0)
+
+	// both batches and the remaining metrics are written in order
+	require.NoError(t, ro.Write())
+	assert.Equal(t, append(first5, next5...), m.Metrics())
 }
 
 func TestRunningOutputWriteFail(t *testing.T) {
@@ -479,13 +489,210 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Verify that the order of points is preserved when metrics are added while
+// failed points are being written.
+func TestRunningOutputWriteFailOrderConcurrentAdd(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 100)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// full batches are added as the failed points are written
+	m.failWrite = false
+	var once sync.Once
+	m.onWrite = func() {
+		once.Do(func() {
+			for _, metric := range next5 {
+				ro.AddMetric(metric)
+			}
+		})
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, first5, m.Metrics())
+
+	require.NoError(t, ro.Write())
+	assert.Equal(t, append(first5, next5...), m.Metrics())
+}
+
+// Verify that metrics which failed to write are kept in the disk buffer and
+// are written by a new RunningOutput using the same buffer path.
+func TestRunningOutputDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "running_output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:     Filter{},
+		BufferPath: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 5, 1000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(5), ro.BufferSize.Get())
+	assert.True(t, ro.BufferBytes.Get() > 0)
+	require.NoError(t, ro.Close())
+
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 5, 1000)
+	defer ro.Close()
+	ro.AddMetric(next5[0])
+	require.NoError(t, ro.Write())
+
+	require.Len(t, m.Metrics(), 6)
+	for i, metric := range append(first5, next5[0]) {
+		assert.Equal(t, metric.String(), m.Metrics()[i].String())
+	}
+}
+
+// Verify that failed writes leave the disk buffer in place, rather than
+// appending the failed batches to it again.
+func TestRunningOutputDiskBufferFailedWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "running_output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:     Filter{},
+		BufferPath: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 1000)
+	defer ro.Close()
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	size := ro.BufferBytes.Get()
+	require.Error(t, ro.Write())
+	assert.Equal(t, size, ro.BufferBytes.Get())
+	assert.Equal(t, int64(5), ro.BufferSize.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+	for i, metric := range first5 {
+		assert.Equal(t, metric.String(), m.Metrics()[i].String())
+	}
+	assert.Equal(t, int64(0), ro.BufferSize.Get())
+}
+
+// Verify that tracked metrics are only delivered once they are written.
+func TestRunningOutputTrackingAccept(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	var infos []telegraf.DeliveryInfo
+	group, _ := metric.NewTrackingMetricGroup(first5,
+		func(info telegraf.DeliveryInfo) { infos = append(infos, info) })
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	for _, metric := range group {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write())
+	assert.Len(t, infos, 0)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+}
+
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			InitialDelay: 50 * time.Millisecond,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write())
+	assert.Equal(t, 1, m.writes)
+
+	// writes are skipped while the circuit is open
+	m.failWrite = false
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 1, m.writes)
+	assert.Equal(t, 10, ro.Status().BufferSize)
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 10)
+	assert.Equal(t, "metric1", m.Metrics()[0].Name())
+	assert.Equal(t, "metric10", m.Metrics()[9].Name())
+	assert.Equal(t, 0, ro.Status().BufferSize)
+}
+
+func TestRunningOutputMaxAttempts(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAttempts: 2,
+		},
+	}
+
+	var infos []telegraf.DeliveryInfo
+	group, _ := metric.NewTrackingMetricGroup(first5,
+		func(info telegraf.DeliveryInfo) { infos = append(infos, info) })
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	for _, metric := range group {
+		ro.AddMetric(metric)
+	}
+	dropped := ro.MetricsDropped.Get()
+
+	require.Error(t, ro.Write())
+	assert.Equal(t, 5, ro.Status().BufferSize)
+	require.Error(t, ro.Write())
+	assert.Equal(t, 0, ro.Status().BufferSize)
+	assert.Equal(t, dropped+5, ro.MetricsDropped.Get())
+
+	require.Len(t, infos, 1)
+	assert.False(t, infos[0].Delivered())
+}
+
 type mockOutput struct {
 	sync.Mutex
 
 	metrics []telegraf.Metric
+	// writes is the number of calls to Write
+	writes int
 
 	// if true, mock a write failure
 	failWrite bool
+	// if set, called on every call to Write
+	onWrite func()
 }
 
 func (m *mockOutput) Connect() error {
@@ -507,6 +714,10 @@ func (m *mockOutput) SampleConfig() string {
 func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	m.Lock()
 	defer m.Unlock()
+	m.writes++
+	if m.onWrite != nil {
+		m.onWrite()
+	}
 	if m.failWrite {
 		return fmt.Errorf("Failed Write!")
 	}
@@ -44,8 +44,23 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 		}
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		out := rp.Processor.Apply(metric)
+		if !containsMetric(out, metric) {
+			// the processor dropped or replaced the metric, so it is done
+			// as far as delivery tracking is concerned.
+			metric.Drop()
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+func containsMetric(metrics []telegraf.Metric, m telegraf.Metric) bool {
+	for _, metric := range metrics {
+		if metric == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+// Package protobuf encodes and decodes metrics as the Metric messages of
+// metric.proto, the schema of the "protobuf" data format. Each message is
+// preceded by its length as a varint.
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Wire types of the protocol buffers encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Numbers of the ValueType enum of metric.proto.
+const (
+	typeUntyped uint64 = iota
+	typeCounter
+	typeGauge
+	typeSummary
+	typeHistogram
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// MarshalDelimited returns the Metric message of a metric preceded by its
+// length. Tags and fields are written sorted by key.
+func MarshalDelimited(m telegraf.Metric) []byte {
+	msg := marshal(m)
+	out := make([]byte, 0, len(msg)+binary.MaxVarintLen64)
+	out = appendVarint(out, uint64(len(msg)))
+	return append(out, msg...)
+}
+
+// UnmarshalDelimited decodes the length-delimited Metric messages of buf.
+func UnmarshalDelimited(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		size, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < size {
+			return nil, errTruncated
+		}
+		m, err := unmarshal(buf[n : n+int(size)])
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+		buf = buf[n+int(size):]
+	}
+	return metrics, nil
+}
+
+// SplitDelimited is a bufio.SplitFunc returning each length-delimited message
+// of a stream, length included, so that the tokens can be given to
+// UnmarshalDelimited.
+func SplitDelimited(data []byte, atEOF bool) (int, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n < 0 {
+		return 0, nil, errors.New("invalid protobuf message length")
+	}
+	if n == 0 || uint64(len(data)-n) < size {
+		if atEOF && len(data) > 0 {
+			return 0, nil, errTruncated
+		}
+		// request more data
+		return 0, nil, nil
+	}
+	end := n + int(size)
+	return end, data[:end], nil
+}
+
+func marshal(m telegraf.Metric) []byte {
+	var msg []byte
+	msg = appendString(msg, 1, m.Name())
+
+	tags := m.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = appendString(entry, 2, tags[k])
+		msg = appendBytes(msg, 2, entry)
+	}
+
+	fields := m.Fields()
+	keys = keys[:0]
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var field []byte
+		field = appendString(field, 1, k)
+		switch v := fields[k].(type) {
+		case float64:
+			field = appendKey(field, 2, wireFixed64)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			field = append(field, b[:]...)
+		case int64:
+			// zigzag encoding of sint64
+			field = appendKey(field, 3, wireVarint)
+			field = appendVarint(field, uint64(v<<1)^uint64(v>>63))
+		case bool:
+			field = appendKey(field, 4, wireVarint)
+			if v {
+				field = appendVarint(field, 1)
+			} else {
+				field = appendVarint(field, 0)
+			}
+		case string:
+			field = appendString(field, 5, v)
+		default:
+			continue
+		}
+		msg = appendBytes(msg, 3, field)
+	}
+
+	msg = appendKey(msg, 4, wireVarint)
+	msg = appendVarint(msg, uint64(m.UnixNano()))
+
+	if t := valueType(m.Type()); t != typeUntyped {
+		msg = appendKey(msg, 5, wireVarint)
+		msg = appendVarint(msg, t)
+	}
+	return msg
+}
+
+// unmarshal decodes a Metric message.
+func unmarshal(msg []byte) (telegraf.Metric, error) {
+	var name string
+	var nsec int64
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	valueType := telegraf.Untyped
+
+	err := decodeMessage(msg, func(field int, wireType int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wireType == wireBytes:
+			name = string(b)
+		case field == 2 && wireType == wireBytes:
+			var key, value string
+			err := decodeMessage(b, func(field int, wireType int, v uint64, b []byte) error {
+				if wireType == wireBytes {
+					switch field {
+					case 1:
+						key = string(b)
+					case 2:
+						value = string(b)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			tags[key] = value
+		case field == 3 && wireType == wireBytes:
+			key, value, err := unmarshalField(b)
+			if err != nil {
+				return err
+			}
+			if value != nil {
+				fields[key] = value
+			}
+		case field == 4 && wireType == wireVarint:
+			nsec = int64(v)
+		case field == 5 && wireType == wireVarint:
+			valueType = metricType(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metric.New(name, tags, fields, time.Unix(0, nsec), valueType)
+}
+
+// unmarshalField decodes a Field message, the value is nil when it is not set
+// or of an unknown type.
+func unmarshalField(msg []byte) (string, interface{}, error) {
+	var key string
+	var value interface{}
+	err := decodeMessage(msg, func(field int, wireType int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wireType == wireBytes:
+			key = string(b)
+		case field == 2 && wireType == wireFixed64:
+			value = math.Float64frombits(v)
+		case field == 3 && wireType == wireVarint:
+			// zigzag encoding of sint64
+			value = int64(v>>1) ^ -int64(v&1)
+		case field == 4 && wireType == wireVarint:
+			value = v != 0
+		case field == 5 && wireType == wireBytes:
+			value = string(b)
+		}
+		return nil
+	})
+	return key, value, err
+}
+
+func valueType(t telegraf.ValueType) uint64 {
+	switch t {
+	case telegraf.Counter:
+		return typeCounter
+	case telegraf.Gauge:
+		return typeGauge
+	case telegraf.Summary:
+		return typeSummary
+	case telegraf.Histogram:
+		return typeHistogram
+	default:
+		return typeUntyped
+	}
+}
+
+func metricType(v uint64) telegraf.ValueType {
+	switch v {
+	case typeCounter:
+		return telegraf.Counter
+	case typeGauge:
+		return telegraf.Gauge
+	case typeSummary:
+		return telegraf.Summary
+	case typeHistogram:
+		return telegraf.Histogram
+	default:
+		return telegraf.Untyped
+	}
+}
+
+// decodeMessage calls fn for each field of msg, with the value of varint and
+// fixed fields in v and the content of length-delimited fields in b.
+func decodeMessage(msg []byte, fn func(field int, wireType int, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errTruncated
+		}
+		msg = msg[n:]
+
+		var v uint64
+		var b []byte
+		wireType := int(key & 7)
+		switch wireType {
+		case wireVarint:
+			v, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errTruncated
+			}
+			msg = msg[n:]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return errTruncated
+			}
+			v = binary.LittleEndian.Uint64(msg)
+			msg = msg[8:]
+		case wireFixed32:
+			if len(msg) < 4 {
+				return errTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(msg))
+			msg = msg[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < size {
+				return errTruncated
+			}
+			b = msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+
+		if err := fn(int(key>>3), wireType, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendKey(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,140 @@
+package protobuf
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// pbMetric and pbField mirror the messages of metric.proto for the protobuf
+// library, the oneof of Field being written as optional fields, which are
+// encoded the same.
+type pbMetric struct {
+	Name   *string           `protobuf:"bytes,1,opt,name=name"`
+	Tags   map[string]string `protobuf:"bytes,2,rep,name=tags" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Fields []*pbField        `protobuf:"bytes,3,rep,name=fields"`
+	Time   *int64            `protobuf:"varint,4,opt,name=time"`
+	Type   *int32            `protobuf:"varint,5,opt,name=type"`
+}
+
+func (m *pbMetric) Reset()         { *m = pbMetric{} }
+func (m *pbMetric) String() string { return proto.CompactTextString(m) }
+func (*pbMetric) ProtoMessage()    {}
+
+type pbField struct {
+	Key         *string  `protobuf:"bytes,1,opt,name=key"`
+	FloatValue  *float64 `protobuf:"fixed64,2,opt,name=float_value"`
+	IntValue    *int64   `protobuf:"zigzag64,3,opt,name=int_value"`
+	BoolValue   *bool    `protobuf:"varint,4,opt,name=bool_value"`
+	StringValue *string  `protobuf:"bytes,5,opt,name=string_value"`
+}
+
+func (f *pbField) Reset()         { *f = pbField{} }
+func (f *pbField) String() string { return proto.CompactTextString(f) }
+func (*pbField) ProtoMessage()    {}
+
+func testMetric(t *testing.T) telegraf.Metric {
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage": float64(91.5),
+			"count": int64(-42),
+			"ok":    true,
+			"state": "running\n",
+		},
+		time.Unix(0, 1500000000000000000),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestMarshalDecodedByLibrary(t *testing.T) {
+	buf := MarshalDelimited(testMetric(t))
+
+	var msg pbMetric
+	err := proto.NewBuffer(buf).DecodeMessage(&msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cpu", *msg.Name)
+	assert.Equal(t, map[string]string{"host": "a", "cpu": "cpu0"}, msg.Tags)
+	assert.Equal(t, int64(1500000000000000000), *msg.Time)
+	assert.Equal(t, int32(typeCounter), *msg.Type)
+
+	fields := make(map[string]interface{})
+	for _, f := range msg.Fields {
+		switch {
+		case f.FloatValue != nil:
+			fields[*f.Key] = *f.FloatValue
+		case f.IntValue != nil:
+			fields[*f.Key] = *f.IntValue
+		case f.BoolValue != nil:
+			fields[*f.Key] = *f.BoolValue
+		case f.StringValue != nil:
+			fields[*f.Key] = *f.StringValue
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"usage": float64(91.5),
+		"count": int64(-42),
+		"ok":    true,
+		"state": "running\n",
+	}, fields)
+}
+
+func TestUnmarshalEncodedByLibrary(t *testing.T) {
+	msg := &pbMetric{
+		Name: proto.String("cpu"),
+		Tags: map[string]string{"host": "a", "cpu": "cpu0"},
+		Fields: []*pbField{
+			{Key: proto.String("usage"), FloatValue: proto.Float64(91.5)},
+			{Key: proto.String("count"), IntValue: proto.Int64(-42)},
+			{Key: proto.String("ok"), BoolValue: proto.Bool(true)},
+			{Key: proto.String("state"), StringValue: proto.String("running\n")},
+		},
+		Time: proto.Int64(1500000000000000000),
+		Type: proto.Int32(int32(typeCounter)),
+	}
+	b := proto.NewBuffer(nil)
+	require.NoError(t, b.EncodeMessage(msg))
+
+	metrics, err := UnmarshalDelimited(b.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	expected := testMetric(t)
+	assert.Equal(t, expected.Name(), metrics[0].Name())
+	assert.Equal(t, expected.Tags(), metrics[0].Tags())
+	assert.Equal(t, expected.Fields(), metrics[0].Fields())
+	assert.Equal(t, expected.UnixNano(), metrics[0].UnixNano())
+	assert.Equal(t, expected.Type(), metrics[0].Type())
+}
+
+func TestSplitDelimited(t *testing.T) {
+	first := MarshalDelimited(testMetric(t))
+	second := MarshalDelimited(testMetric(t))
+
+	scnr := bufio.NewScanner(bytes.NewReader(append(first, second...)))
+	scnr.Split(SplitDelimited)
+	var tokens [][]byte
+	for scnr.Scan() {
+		tokens = append(tokens, append([]byte(nil), scnr.Bytes()...))
+	}
+	require.NoError(t, scnr.Err())
+	assert.Equal(t, [][]byte{first, second}, tokens)
+
+	// a message cut short by the end of the stream is an error
+	scnr = bufio.NewScanner(bytes.NewReader(first[:len(first)-1]))
+	scnr.Split(SplitDelimited)
+	assert.False(t, scnr.Scan())
+	assert.Equal(t, errTruncated, scnr.Err())
+}
@@ -0,0 +1,193 @@
+// Package secret resolves the secret references of the configuration, and
+// redacts the resolved secrets from the output of Telegraf.
+//
+// A reference has the form @{store:key}, where store is the name of a
+// registered Store. The env, file and exec stores are always available:
+//
+//	@{env:INFLUX_PASSWORD}             the INFLUX_PASSWORD environment variable
+//	@{file:/run/secrets/influx}        the contents of a file
+//	@{exec:/usr/bin/get-secret influx} the standard output of a command
+//
+// A reference is escaped by doubling its @: @@{env:HOME} stands for the
+// literal text @{env:HOME}.
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// ExecTimeout is the time a command of the exec store has to complete.
+const ExecTimeout = 10 * time.Second
+
+// Redacted replaces the secrets removed by Redact.
+const Redacted = "[REDACTED]"
+
+// Store is a source of secrets.
+type Store interface {
+	// Get returns the secret of the given key.
+	Get(key string) (string, error)
+}
+
+// StoreFunc is an adapter to use a function as a Store.
+type StoreFunc func(key string) (string, error)
+
+// Get calls f(key).
+func (f StoreFunc) Get(key string) (string, error) {
+	return f(key)
+}
+
+// refRe matches the references, and the escaped references starting with @@.
+var refRe = regexp.MustCompile(`@?@\{(\w+):([^}]*)\}`)
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]Store{
+		"env":  StoreFunc(getEnv),
+		"file": StoreFunc(getFile),
+		"exec": StoreFunc(getExec),
+	}
+
+	// secrets are the resolved secrets, longest first
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddStore registers a store under the given name, for it to be used in
+// references.
+func AddStore(name string, store Store) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = store
+}
+
+// HasReference returns true if s contains a secret reference, or an escaped
+// one.
+func HasReference(s string) bool {
+	return refRe.MatchString(s)
+}
+
+// Resolve replaces the secret references in s by their secret, and the
+// escaped references by their literal text. Resolved secrets are redacted by
+// Redact from then on.
+func Resolve(s string) (string, error) {
+	var err error
+	resolved := refRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		if strings.HasPrefix(ref, "@@") {
+			return ref[1:]
+		}
+		m := refRe.FindStringSubmatch(ref)
+		storesMu.RLock()
+		store, ok := stores[m[1]]
+		storesMu.RUnlock()
+		if !ok {
+			err = fmt.Errorf("unknown secret store %q in %s", m[1], ref)
+			return ""
+		}
+
+		var value string
+		value, err = store.Get(m[2])
+		if err != nil {
+			err = fmt.Errorf("could not resolve secret %s: %s", ref, err)
+			return ""
+		}
+		addSecret(value)
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func addSecret(value string) {
+	if value == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+	// longer secrets first, in case one contains another
+	sort.SliceStable(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Redact replaces the resolved secrets in s by Redacted.
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
+
+// RedactBytes is like Redact, but for a byte slice. b is returned as is when
+// it contains no secret.
+func RedactBytes(b []byte) []byte {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		if bytes.Contains(b, []byte(secret)) {
+			b = bytes.Replace(b, []byte(secret), []byte(Redacted), -1)
+		}
+	}
+	return b
+}
+
+func getEnv(key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return value, nil
+}
+
+// getFile returns the contents of a file, without its trailing newline.
+func getFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// getExec runs a command, which is split on spaces, and returns its standard
+// output without its trailing newline.
+func getExec(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("no command given")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := internal.RunTimeout(cmd, ExecTimeout); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEnv(t *testing.T) {
+	os.Setenv("TEST_SECRET_PASSWORD", "hunter2")
+	defer os.Unsetenv("TEST_SECRET_PASSWORD")
+
+	s, err := Resolve("user:@{env:TEST_SECRET_PASSWORD}@localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "user:hunter2@localhost", s)
+
+	_, err = Resolve("@{env:TEST_SECRET_UNSET}")
+	assert.EqualError(t, err, "could not resolve secret @{env:TEST_SECRET_UNSET}: "+
+		"environment variable TEST_SECRET_UNSET is not set")
+}
+
+func TestResolveFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("s3cr3t-from-file\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err := Resolve("@{file:" + f.Name() + "}")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-from-file", s)
+
+	_, err = Resolve("@{file:/nonexistent/secret}")
+	assert.Error(t, err)
+}
+
+func TestResolveExec(t *testing.T) {
+	s, err := Resolve("@{exec:echo s3cr3t-from-exec}")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-from-exec", s)
+
+	_, err = Resolve("@{exec:false}")
+	assert.Error(t, err)
+}
+
+func TestResolveStore(t *testing.T) {
+	AddStore("test", StoreFunc(func(key string) (string, error) {
+		if key == "missing" {
+			return "", errors.New("not found")
+		}
+		return "store-" + key, nil
+	}))
+
+	s, err := Resolve("@{test:a} and @{test:b}")
+	require.NoError(t, err)
+	assert.Equal(t, "store-a and store-b", s)
+
+	_, err = Resolve("@{test:missing}")
+	assert.EqualError(t, err, "could not resolve secret @{test:missing}: not found")
+
+	_, err = Resolve("@{unknown:a}")
+	assert.EqualError(t, err, `unknown secret store "unknown" in @{unknown:a}`)
+}
+
+func TestResolveNoReference(t *testing.T) {
+	for _, s := range []string{"", "plain", "user@host", "@{}", "${HOME}"} {
+		assert.False(t, HasReference(s), s)
+		resolved, err := Resolve(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, resolved)
+	}
+}
+
+func TestResolveEscaped(t *testing.T) {
+	os.Setenv("TEST_SECRET_ESCAPED", "hunter2")
+	defer os.Unsetenv("TEST_SECRET_ESCAPED")
+
+	assert.True(t, HasReference("p@@{env:TEST_SECRET_ESCAPED}"))
+	s, err := Resolve("p@@{env:TEST_SECRET_ESCAPED}")
+	require.NoError(t, err)
+	assert.Equal(t, "p@{env:TEST_SECRET_ESCAPED}", s)
+
+	// escaped references are left unresolved, even with an unknown store
+	s, err = Resolve("@@{unknown:a} @{env:TEST_SECRET_ESCAPED}")
+	require.NoError(t, err)
+	assert.Equal(t, "@{unknown:a} hunter2", s)
+}
+
+func TestRedact(t *testing.T) {
+	AddStore("redact", StoreFunc(func(key string) (string, error) {
+		return key, nil
+	}))
+	_, err := Resolve("@{redact:topsecret} @{redact:topsecret-longer}")
+	require.NoError(t, err)
+
+	assert.Equal(t, "password=[REDACTED] other=[REDACTED]",
+		Redact("password=topsecret other=topsecret-longer"))
+	assert.Equal(t, []byte("dsn=[REDACTED]@tcp"),
+		RedactBytes([]byte("dsn=topsecret@tcp")))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
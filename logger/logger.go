@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/wlog"
 )
 
@@ -30,7 +31,7 @@ func (t *telegrafLog) Write(b []byte) (n int, err error) {
 	} else {
 		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
 	}
-	return t.writer.Write(line)
+	return t.writer.Write(secret.RedactBytes(line))
 }
 
 // SetupLogging configures the logging output.
@@ -61,4 +61,19 @@ type Metric interface {
 	// aggregator things:
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// Delivery tracking functions, these are a no-op for untracked metrics.
+	// Each copy of a tracked metric must be resolved with exactly one of
+	// Accept, Reject or Drop.
+
+	// Accept marks the metric as written successfully to an output.
+	Accept()
+
+	// Reject marks the metric as not delivered, ie, because it was dropped
+	// from a full buffer.
+	Reject()
+
+	// Drop marks the metric as processed without being written to an
+	// output, ie, because it was filtered or consumed by an aggregator.
+	Drop()
 }
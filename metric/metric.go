@@ -189,6 +189,15 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+// Accept is a no-op, as untracked metrics are not followed through delivery.
+func (m *metric) Accept() {}
+
+// Reject is a no-op, as untracked metrics are not followed through delivery.
+func (m *metric) Reject() {}
+
+// Drop is a no-op, as untracked metrics are not followed through delivery.
+func (m *metric) Drop() {}
+
 func (m *metric) Type() telegraf.ValueType {
 	return m.mType
 }
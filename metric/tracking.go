@@ -0,0 +1,134 @@
+package metric
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called once all the metrics of a tracked group have been
+// resolved.
+type NotifyFunc func(telegraf.DeliveryInfo)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
+// trackingData is shared between all the metrics of a group, and all of
+// their copies.
+type trackingData struct {
+	id telegraf.TrackingID
+	// number of unresolved metrics referencing this group
+	rc       int32
+	rejected int32
+	notify   NotifyFunc
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.rc, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.rc, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+// trackingMetric wraps a metric with the delivery tracking of its group.
+type trackingMetric struct {
+	telegraf.Metric
+	d *trackingData
+	// set once this metric has been resolved, or its tracking has been
+	// handed over to another metric.
+	done int32
+}
+
+// NewTrackingMetricGroup wraps the metrics of group so that notify is called
+// once every one of them, and every copy made of them, has been accepted,
+// rejected or dropped.
+func NewTrackingMetricGroup(
+	group []telegraf.Metric,
+	notify NotifyFunc,
+) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:     newTrackingID(),
+		rc:     int32(len(group)),
+		notify: notify,
+	}
+
+	if len(group) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return group, d.id
+	}
+
+	out := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		out = append(out, &trackingMetric{Metric: m, d: d})
+	}
+	return out, d.id
+}
+
+// WithTrackingFrom returns m carrying the delivery tracking of src. This is
+// used where a metric is rebuilt from another one, so that resolving the
+// new metric resolves the original. If src is not tracked, m is returned
+// as is.
+func WithTrackingFrom(src, m telegraf.Metric) telegraf.Metric {
+	tm, ok := src.(*trackingMetric)
+	if !ok {
+		return m
+	}
+	if !atomic.CompareAndSwapInt32(&tm.done, 0, 1) {
+		return m
+	}
+	if m == nil {
+		tm.d.decr()
+		return nil
+	}
+	return &trackingMetric{Metric: m, d: tm.d}
+}
+
+// Copy deep-copies the metric, the copy must be resolved separately.
+func (m *trackingMetric) Copy() telegraf.Metric {
+	m.d.incr()
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+func (m *trackingMetric) Accept() {
+	m.resolve(false)
+}
+
+func (m *trackingMetric) Reject() {
+	m.resolve(true)
+}
+
+func (m *trackingMetric) Drop() {
+	m.resolve(false)
+}
+
+func (m *trackingMetric) resolve(rejected bool) {
+	if !atomic.CompareAndSwapInt32(&m.done, 0, 1) {
+		return
+	}
+	if rejected {
+		atomic.StoreInt32(&m.d.rejected, 1)
+	}
+	m.d.decr()
+}
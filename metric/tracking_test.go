@@ -0,0 +1,91 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustMetric(t *testing.T, name string) telegraf.Metric {
+	m, err := New(name,
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+type deliveries struct {
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) notify(info telegraf.DeliveryInfo) {
+	d.infos = append(d.infos, info)
+}
+
+func TestTrackingGroupAccepted(t *testing.T) {
+	d := &deliveries{}
+	group, id := NewTrackingMetricGroup(
+		[]telegraf.Metric{mustMetric(t, "cpu"), mustMetric(t, "mem")}, d.notify)
+	require.Len(t, group, 2)
+
+	cp := group[0].Copy()
+	group[0].Accept()
+	group[1].Drop()
+	assert.Len(t, d.infos, 0)
+
+	cp.Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+
+	// resolving a metric twice has no effect
+	cp.Reject()
+	assert.Len(t, d.infos, 1)
+}
+
+func TestTrackingGroupRejected(t *testing.T) {
+	d := &deliveries{}
+	group, _ := NewTrackingMetricGroup(
+		[]telegraf.Metric{mustMetric(t, "cpu"), mustMetric(t, "mem")}, d.notify)
+
+	group[0].Reject()
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	d := &deliveries{}
+	group, id := NewTrackingMetricGroup(nil, d.notify)
+	assert.Len(t, group, 0)
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestWithTrackingFrom(t *testing.T) {
+	d := &deliveries{}
+	group, _ := NewTrackingMetricGroup(
+		[]telegraf.Metric{mustMetric(t, "cpu")}, d.notify)
+
+	// untracked metrics are returned as is
+	m := mustMetric(t, "mem")
+	assert.Equal(t, m, WithTrackingFrom(m, m))
+
+	rebuilt := WithTrackingFrom(group[0], mustMetric(t, "disk"))
+	assert.Equal(t, "disk", rebuilt.Name())
+
+	// the original no longer holds a reference
+	group[0].Accept()
+	assert.Len(t, d.infos, 0)
+
+	rebuilt.Accept()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
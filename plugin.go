@@ -0,0 +1,10 @@
+package telegraf
+
+// Initializer is an interface that inputs, outputs, processors and
+// aggregators can optionally implement to validate and prepare their
+// configuration once it has been loaded.
+type Initializer interface {
+	// Init is called once after the plugin has been configured; an error
+	// aborts loading the configuration.
+	Init() error
+}
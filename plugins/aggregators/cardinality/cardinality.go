@@ -0,0 +1,392 @@
+package cardinality
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// otherValue is the value of the tags which differ between the series folded
+// together.
+const otherValue = "other"
+
+// Cardinality limits the number of series of each measurement. The series of
+// a measurement are held until there are more than the limit in a period,
+// the measurement then only holds its top k series, the others being folded
+// together or dropped, and its distinct series are estimated with a
+// HyperLogLog sketch.
+type Cardinality struct {
+	Limit     int    `toml:"limit"`
+	TopK      int    `toml:"top_k"`
+	RankField string `toml:"rank_field"`
+	Overflow  string `toml:"overflow"`
+
+	cache map[string]*measurement
+}
+
+// measurement holds the series of a measurement seen in the period. Once it
+// has more series than the limit, it holds its top k series in a heap, and
+// the other series are folded in other.
+type measurement struct {
+	series map[uint64]*series
+
+	// the fields below are set once the limit is passed
+	sketch *hyperLogLog
+	top    *seriesHeap
+	other  *other
+}
+
+// series holds the last value of the fields of a series, the sum of its
+// numeric fields, and its number of metrics.
+type series struct {
+	id     uint64
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	sums   map[string]interface{}
+	time   time.Time
+	count  int64
+
+	// rank and index are maintained once the series is in a heap
+	rank  float64
+	index int
+}
+
+func NewCardinality() telegraf.Aggregator {
+	c := &Cardinality{
+		Limit:    1000,
+		TopK:     100,
+		Overflow: "fold",
+	}
+	c.Reset()
+	return c
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins. The series are
+  ## only limited when true, as the aggregator emits each series kept once
+  ## per period: the metrics it aggregates are downsampled, even when under
+  ## the limit, so only select the measurements at risk with namepass.
+  drop_original = false
+  # namepass = ["http"]
+
+  ## Number of distinct series of a measurement in a period above which
+  ## only its top_k series are kept.
+  # limit = 1000
+  # top_k = 100
+
+  ## Field ranking the series, the series with the largest last value are
+  ## kept. The series with the most metrics are kept when empty.
+  # rank_field = ""
+
+  ## Series not kept are either folded into a series, whose tags differing
+  ## between them are "other" and whose fields are summed, with "fold", or
+  ## dropped with "drop".
+  # overflow = "fold"
+`
+
+func (c *Cardinality) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Cardinality) Description() string {
+	return "Limit the number of series of each measurement to its top k series."
+}
+
+// Init validates the limits and the overflow.
+func (c *Cardinality) Init() error {
+	if c.Limit < 1 {
+		return fmt.Errorf("limit must be at least 1, got %d", c.Limit)
+	}
+	if c.TopK < 1 || c.TopK > c.Limit {
+		return fmt.Errorf("top_k must be between 1 and limit, got %d", c.TopK)
+	}
+	switch c.Overflow {
+	case "fold", "drop":
+	default:
+		return fmt.Errorf("invalid overflow %q, must be fold or drop", c.Overflow)
+	}
+	return nil
+}
+
+func (c *Cardinality) Add(in telegraf.Metric) {
+	m, ok := c.cache[in.Name()]
+	if !ok {
+		m = &measurement{series: make(map[uint64]*series)}
+		c.cache[in.Name()] = m
+	}
+
+	id := in.HashID()
+	if m.sketch != nil {
+		m.sketch.add(mix(id))
+	}
+	if s, ok := m.series[id]; ok {
+		s.add(in)
+		if m.top != nil {
+			s.rank = c.rankOf(s)
+			heap.Fix(m.top, s.index)
+		}
+		return
+	}
+
+	s := &series{
+		id:     id,
+		name:   in.Name(),
+		tags:   in.Tags(),
+		fields: make(map[string]interface{}),
+		sums:   make(map[string]interface{}),
+	}
+	if m.top == nil && len(m.series) < c.Limit {
+		s.add(in)
+		m.series[id] = s
+		return
+	}
+	if m.top == nil {
+		c.overflow(m)
+		m.sketch.add(mix(id))
+	}
+
+	// The new series takes the place of the lowest ranked series when it
+	// ranks higher. Without rank field, it takes its number of metrics as in
+	// the space-saving algorithm, so that frequent series seen late are kept.
+	lowest := (*m.top)[0]
+	if c.RankField == "" {
+		s.count = lowest.count
+	}
+	s.add(in)
+	s.rank = c.rankOf(s)
+	if !seriesLess(lowest, s) {
+		c.fold(m, s)
+		return
+	}
+	heap.Pop(m.top)
+	delete(m.series, lowest.id)
+	c.fold(m, lowest)
+	m.series[id] = s
+	heap.Push(m.top, s)
+}
+
+// overflow starts estimating the series of a measurement, and cuts it down
+// to its top k series, the others being folded.
+func (c *Cardinality) overflow(m *measurement) {
+	m.sketch = &hyperLogLog{}
+	m.other = &other{}
+
+	list := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		m.sketch.add(mix(s.id))
+		s.rank = c.rankOf(s)
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return seriesLess(list[j], list[i])
+	})
+
+	top := seriesHeap(list[:c.TopK])
+	for i, s := range top {
+		s.index = i
+	}
+	for _, s := range list[c.TopK:] {
+		delete(m.series, s.id)
+		c.fold(m, s)
+	}
+	heap.Init(&top)
+	m.top = &top
+}
+
+// fold folds the metrics of a series not kept into other, unless they are
+// dropped.
+func (c *Cardinality) fold(m *measurement, s *series) {
+	if c.Overflow == "fold" {
+		m.other.add(s)
+	}
+}
+
+func (c *Cardinality) Push(acc telegraf.Accumulator) {
+	for name, m := range c.cache {
+		for _, s := range m.series {
+			acc.AddFields(s.name, s.fields, s.tags, s.time)
+		}
+
+		count := uint64(len(m.series))
+		if m.sketch != nil {
+			// the estimate can be below the number of series seen
+			count = m.sketch.count()
+			if count <= uint64(c.Limit) {
+				count = uint64(c.Limit) + 1
+			}
+			if m.other.series > 0 {
+				acc.AddFields(m.other.name, m.other.fields, m.other.tags, m.other.time)
+			}
+		}
+
+		acc.AddFields("cardinality", map[string]interface{}{
+			"series":   int64(count),
+			"overflow": int64(count) - int64(len(m.series)),
+		}, map[string]string{"measurement": name})
+	}
+}
+
+func (c *Cardinality) Reset() {
+	c.cache = make(map[string]*measurement)
+}
+
+// rankOf returns the value of the rank field of a series, or its number of
+// metrics when there is no rank field. Series without a numeric rank field
+// rank last.
+func (c *Cardinality) rankOf(s *series) float64 {
+	if c.RankField == "" {
+		return float64(s.count)
+	}
+	if v, ok := convert(s.fields[c.RankField]); ok && !math.IsNaN(v) {
+		return v
+	}
+	return math.Inf(-1)
+}
+
+// add adds the fields of a metric to the series.
+func (s *series) add(in telegraf.Metric) {
+	for k, v := range in.Fields() {
+		s.fields[k] = v
+		if total, ok := s.sums[k]; ok {
+			s.sums[k] = sum(total, v)
+		} else if _, ok := convert(v); ok {
+			s.sums[k] = v
+		}
+	}
+	if in.Time().After(s.time) {
+		s.time = in.Time()
+	}
+	s.count++
+}
+
+// seriesLess orders the series by increasing rank, and by decreasing id for
+// equal ranks.
+func seriesLess(a, b *series) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	return a.id > b.id
+}
+
+// seriesHeap is a min-heap of series by rank.
+type seriesHeap []*series
+
+func (h seriesHeap) Len() int { return len(h) }
+
+func (h seriesHeap) Less(i, j int) bool { return seriesLess(h[i], h[j]) }
+
+func (h seriesHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *seriesHeap) Push(x interface{}) {
+	s := x.(*series)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+
+func (h *seriesHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return s
+}
+
+// other accumulates the series not kept, the tags with the same value in all
+// the series are kept, the others are "other". The numeric fields of their
+// metrics are summed, keeping the type of the field in the first series
+// having it.
+type other struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+	// series is the number of series folded
+	series int
+}
+
+func (o *other) add(s *series) {
+	if o.series == 0 {
+		o.name = s.name
+		o.tags = make(map[string]string, len(s.tags))
+		for k, v := range s.tags {
+			o.tags[k] = v
+		}
+		o.fields = make(map[string]interface{}, len(s.sums))
+	} else {
+		for k, v := range o.tags {
+			if sv, ok := s.tags[k]; !ok || sv != v {
+				o.tags[k] = otherValue
+			}
+		}
+		// tags missing in the previous series differ as well
+		for k := range s.tags {
+			if _, ok := o.tags[k]; !ok {
+				o.tags[k] = otherValue
+			}
+		}
+	}
+
+	for k, v := range s.sums {
+		if total, ok := o.fields[k]; ok {
+			o.fields[k] = sum(total, v)
+		} else {
+			o.fields[k] = v
+		}
+	}
+	if s.time.After(o.time) {
+		o.time = s.time
+	}
+	o.series++
+}
+
+// sum returns total + v when they have the same type, or total.
+func sum(total, v interface{}) interface{} {
+	switch t := total.(type) {
+	case int64:
+		if x, ok := v.(int64); ok {
+			return t + x
+		}
+	case uint64:
+		if x, ok := v.(uint64); ok {
+			return t + x
+		}
+	case float64:
+		if x, ok := v.(float64); ok {
+			return t + x
+		}
+	}
+	return total
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("cardinality", func() telegraf.Aggregator {
+		return NewCardinality()
+	})
+}
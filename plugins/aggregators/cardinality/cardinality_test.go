@@ -0,0 +1,226 @@
+package cardinality
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var now = time.Date(2017, time.December, 10, 12, 0, 0, 0, time.UTC)
+
+var a1, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/a"},
+	map[string]interface{}{"requests": int64(1)},
+	now,
+)
+var a2, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/a"},
+	map[string]interface{}{"requests": int64(2)},
+	now,
+)
+var a3, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/a"},
+	map[string]interface{}{"requests": int64(3)},
+	now,
+)
+var b1, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/b"},
+	map[string]interface{}{"requests": int64(1)},
+	now,
+)
+var b2, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/b"},
+	map[string]interface{}{"requests": int64(2)},
+	now,
+)
+var b3, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/b"},
+	map[string]interface{}{"requests": int64(3)},
+	now,
+)
+var hot, _ = metric.New("http",
+	map[string]string{"host": "server01", "path": "/hot"},
+	map[string]interface{}{"requests": int64(1)},
+	now,
+)
+
+func TestUnderLimit(t *testing.T) {
+	c := &Cardinality{Limit: 2, TopK: 1, Overflow: "fold"}
+	c.Reset()
+	c.Add(a1)
+	c.Add(a2)
+	c.Add(b3)
+
+	acc := testutil.Accumulator{}
+	c.Push(&acc)
+	assert.Equal(t, uint64(3), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(2)},
+		map[string]string{"host": "server01", "path": "/a"})
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(3)},
+		map[string]string{"host": "server01", "path": "/b"})
+	acc.AssertContainsTaggedFields(t, "cardinality",
+		map[string]interface{}{"series": int64(2), "overflow": int64(0)},
+		map[string]string{"measurement": "http"})
+}
+
+func TestOverLimitFold(t *testing.T) {
+	c := &Cardinality{Limit: 3, TopK: 2, RankField: "requests", Overflow: "fold"}
+	c.Reset()
+	for i := 1; i <= 5; i++ {
+		m, _ := metric.New("http",
+			map[string]string{"host": "server01", "path": fmt.Sprintf("/%d", i)},
+			map[string]interface{}{
+				"requests": int64(i),
+				"latency":  float64(i) / 10,
+				"status":   "ok",
+			},
+			now,
+		)
+		c.Add(m)
+	}
+
+	acc := testutil.Accumulator{}
+	c.Push(&acc)
+	assert.Equal(t, uint64(4), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(5), "latency": 0.5, "status": "ok"},
+		map[string]string{"host": "server01", "path": "/5"})
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(4), "latency": 0.4, "status": "ok"},
+		map[string]string{"host": "server01", "path": "/4"})
+	// the tags which differ are "other", the numeric fields are summed
+	var folded *testutil.Metric
+	for _, p := range acc.Metrics {
+		if p.Tags["path"] == "other" {
+			folded = p
+		}
+	}
+	require.NotNil(t, folded)
+	assert.Equal(t, map[string]string{"host": "server01", "path": "other"}, folded.Tags)
+	assert.Equal(t, int64(6), folded.Fields["requests"])
+	assert.InDelta(t, 0.6, folded.Fields["latency"], 1e-9)
+	assert.NotContains(t, folded.Fields, "status")
+	acc.AssertContainsTaggedFields(t, "cardinality",
+		map[string]interface{}{"series": int64(5), "overflow": int64(3)},
+		map[string]string{"measurement": "http"})
+}
+
+func TestOverLimitDrop(t *testing.T) {
+	c := &Cardinality{Limit: 1, TopK: 1, Overflow: "drop"}
+	c.Reset()
+	// series are ranked by their number of metrics without rank field
+	c.Add(a1)
+	c.Add(b1)
+	c.Add(b2)
+
+	acc := testutil.Accumulator{}
+	c.Push(&acc)
+	assert.Equal(t, uint64(2), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(2)},
+		map[string]string{"host": "server01", "path": "/b"})
+	acc.AssertContainsTaggedFields(t, "cardinality",
+		map[string]interface{}{"series": int64(2), "overflow": int64(1)},
+		map[string]string{"measurement": "http"})
+
+	// the limit applies to each period
+	c.Reset()
+	c.Add(a3)
+	acc.ClearMetrics()
+	c.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(3)},
+		map[string]string{"host": "server01", "path": "/a"})
+}
+
+func TestFoldTags(t *testing.T) {
+	a, _ := metric.New("m", map[string]string{"host": "a", "dc": "east"},
+		map[string]interface{}{"value": int64(1)}, now)
+	b, _ := metric.New("m", map[string]string{"host": "a"},
+		map[string]interface{}{"value": int64(2), "ratio": 0.5}, now.Add(time.Second))
+	c, _ := metric.New("m", map[string]string{"host": "a", "rack": "1"},
+		map[string]interface{}{"value": int64(4), "state": "ok"}, now)
+
+	var folded other
+	for _, m := range []telegraf.Metric{a, b, c} {
+		s := &series{
+			name:   m.Name(),
+			tags:   m.Tags(),
+			fields: make(map[string]interface{}),
+			sums:   make(map[string]interface{}),
+		}
+		s.add(m)
+		folded.add(s)
+	}
+	assert.Equal(t, map[string]string{"host": "a", "dc": "other", "rack": "other"}, folded.tags)
+	assert.Equal(t, map[string]interface{}{"value": int64(7), "ratio": 0.5}, folded.fields)
+	assert.True(t, now.Add(time.Second).Equal(folded.time))
+	assert.Equal(t, 3, folded.series)
+}
+
+func TestOverLimitBounded(t *testing.T) {
+	c := &Cardinality{Limit: 10, TopK: 2, Overflow: "fold"}
+	c.Reset()
+	// a frequent series seen once the limit is passed is kept
+	for i := 0; i < 1000; i++ {
+		m, _ := metric.New("http",
+			map[string]string{"host": "server01", "path": fmt.Sprintf("/%d", i)},
+			map[string]interface{}{"requests": int64(1)},
+			now,
+		)
+		c.Add(m)
+		if i >= 500 {
+			c.Add(hot)
+		}
+	}
+
+	// only the top k series are held once over the limit
+	m := c.cache["http"]
+	assert.Len(t, m.series, 2)
+	assert.Len(t, *m.top, 2)
+
+	acc := testutil.Accumulator{}
+	c.Push(&acc)
+	assert.Equal(t, uint64(4), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"requests": int64(1)},
+		map[string]string{"host": "server01", "path": "/hot"})
+
+	// the series not kept are folded together
+	var folded int64
+	for _, p := range acc.Metrics {
+		if p.Measurement == "http" && p.Tags["path"] == "other" {
+			assert.Equal(t, "server01", p.Tags["host"])
+			folded = p.Fields["requests"].(int64)
+		}
+	}
+	assert.True(t, folded > 900, "folded %d requests", folded)
+
+	series, ok := acc.Get("cardinality")
+	require.True(t, ok)
+	estimate := series.Fields["series"].(int64)
+	assert.InDelta(t, 1001, estimate, 1001*0.05)
+	assert.Equal(t, estimate-2, series.Fields["overflow"])
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, c := range []*Cardinality{
+		{Limit: 0, TopK: 1, Overflow: "fold"},
+		{Limit: 10, TopK: 0, Overflow: "fold"},
+		{Limit: 10, TopK: 11, Overflow: "fold"},
+		{Limit: 10, TopK: 1, Overflow: "other"},
+	} {
+		assert.Error(t, c.Init(), "%+v", c)
+	}
+	c := &Cardinality{Limit: 10, TopK: 10, Overflow: "drop"}
+	assert.NoError(t, c.Init())
+}
@@ -0,0 +1,76 @@
+package cardinality
+
+import (
+	"math"
+)
+
+// precision is the number of bits of the hashes indexing the registers, the
+// standard error of the estimates is 1.04/sqrt(2^precision), about 0.8%.
+const precision = 14
+
+const registers = 1 << precision
+
+// hyperLogLog estimates the number of distinct hashes added to it in a
+// constant memory. Each hash is added to the register indexed by its first
+// bits, which keeps the longest run of leading zeros seen in the remaining
+// bits.
+//
+// See Flajolet et al., HyperLogLog: the analysis of a near-optimal
+// cardinality estimation algorithm.
+type hyperLogLog struct {
+	registers [registers]uint8
+}
+
+// add adds a hash to the sketch, the hashes must be uniformly distributed.
+func (h *hyperLogLog) add(hash uint64) {
+	i := hash >> (64 - precision)
+	// the guard bit bounds the run of zeros of the remaining bits
+	w := hash<<precision | 1<<(precision-1)
+	rank := leadingZeros(w) + 1
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// count returns the estimated number of distinct hashes added to the
+// sketch. Small cardinalities are estimated by linear counting of the empty
+// registers, which is more accurate.
+func (h *hyperLogLog) count() uint64 {
+	m := float64(registers)
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// leadingZeros returns the number of leading zero bits of w, which must not
+// be zero.
+func leadingZeros(w uint64) uint8 {
+	var n uint8
+	for ; w&(1<<63) == 0; w <<= 1 {
+		n++
+	}
+	return n
+}
+
+// mix spreads the bits of a hash, so that the first bits of similar hashes
+// are uniformly distributed.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
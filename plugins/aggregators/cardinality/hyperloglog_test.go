@@ -0,0 +1,28 @@
+package cardinality
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLogCount(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 50000, 1000000} {
+		h := &hyperLogLog{}
+		for i := 0; i < n; i++ {
+			h.add(mix(uint64(i)))
+			// duplicates are not counted
+			h.add(mix(uint64(i)))
+		}
+		estimate := float64(h.count())
+		assert.InDelta(t, n, estimate, math.Max(1, 0.03*float64(n)), "n=%d", n)
+	}
+}
+
+func TestLeadingZeros(t *testing.T) {
+	assert.Equal(t, uint8(0), leadingZeros(1<<63))
+	assert.Equal(t, uint8(0), leadingZeros(math.MaxUint64))
+	assert.Equal(t, uint8(13), leadingZeros(1<<50|1))
+	assert.Equal(t, uint8(63), leadingZeros(1))
+}
@@ -0,0 +1,88 @@
+package merge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Merge merges the metrics with the same name, tags and timestamp into a
+// metric with the union of their fields.
+type Merge struct {
+	cache map[key]*aggregate
+	// order is the order in which the merged metrics were first seen.
+	order []*aggregate
+}
+
+// key identifies the metrics merged together.
+type key struct {
+	id   uint64
+	time int64
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+func NewMerge() telegraf.Aggregator {
+	m := &Merge{}
+	m.Reset()
+	return m
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+`
+
+func (m *Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Merge) Description() string {
+	return "Merge metrics with the same name, tags and timestamp into one metric."
+}
+
+func (m *Merge) Add(in telegraf.Metric) {
+	k := key{id: in.HashID(), time: in.UnixNano()}
+	a, ok := m.cache[k]
+	if !ok {
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]interface{}),
+			time:   in.Time(),
+		}
+		m.cache[k] = a
+		m.order = append(m.order, a)
+	}
+	// the value of the latest metric wins when fields are repeated
+	for k, v := range in.Fields() {
+		a.fields[k] = v
+	}
+}
+
+func (m *Merge) Push(acc telegraf.Accumulator) {
+	for _, a := range m.order {
+		acc.AddFields(a.name, a.fields, a.tags, a.time)
+	}
+}
+
+func (m *Merge) Reset() {
+	m.cache = make(map[key]*aggregate)
+	m.order = nil
+}
+
+func init() {
+	aggregators.Add("merge", func() telegraf.Aggregator {
+		return NewMerge()
+	})
+}
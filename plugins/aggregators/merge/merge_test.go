@@ -0,0 +1,90 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var now = time.Date(2017, time.December, 10, 12, 0, 0, 0, time.UTC)
+
+var m1, _ = metric.New("cpu",
+	map[string]string{"host": "server01"},
+	map[string]interface{}{
+		"usage_user": 10.5,
+	},
+	now,
+)
+var m2, _ = metric.New("cpu",
+	map[string]string{"host": "server01"},
+	map[string]interface{}{
+		"usage_system": 2.5,
+		"count":        int64(1),
+	},
+	now,
+)
+var m3, _ = metric.New("cpu",
+	map[string]string{"host": "server01"},
+	map[string]interface{}{
+		"count": int64(2),
+	},
+	now,
+)
+var a1, _ = metric.New("cpu",
+	map[string]string{"host": "server01"},
+	map[string]interface{}{"a": int64(1)},
+	now,
+)
+var b2, _ = metric.New("cpu",
+	map[string]string{"host": "server02"},
+	map[string]interface{}{"b": int64(2)},
+	now,
+)
+var c3, _ = metric.New("cpu",
+	map[string]string{"host": "server01"},
+	map[string]interface{}{"c": int64(3)},
+	now.Add(time.Second),
+)
+
+func TestMergeSameSeriesAndTime(t *testing.T) {
+	m := &Merge{}
+	m.Reset()
+	m.Add(m1)
+	m.Add(m2)
+	m.Add(m3)
+
+	acc := testutil.Accumulator{}
+	m.Push(&acc)
+	assert.Equal(t, uint64(1), acc.NMetrics())
+	// the value of the latest metric wins
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{
+		"usage_user":   10.5,
+		"usage_system": 2.5,
+		"count":        int64(2),
+	}, map[string]string{"host": "server01"})
+	assert.True(t, now.Equal(acc.Metrics[0].Time))
+}
+
+func TestMergeKeepsDifferentSeriesAndTimes(t *testing.T) {
+	m := &Merge{}
+	m.Reset()
+	m.Add(a1)
+	m.Add(b2)
+	m.Add(c3)
+
+	acc := testutil.Accumulator{}
+	m.Push(&acc)
+	assert.Equal(t, uint64(3), acc.NMetrics())
+	assert.Equal(t, map[string]interface{}{"a": int64(1)}, acc.Metrics[0].Fields)
+	assert.Equal(t, map[string]interface{}{"b": int64(2)}, acc.Metrics[1].Fields)
+	assert.Equal(t, map[string]interface{}{"c": int64(3)}, acc.Metrics[2].Fields)
+
+	m.Reset()
+	acc.ClearMetrics()
+	m.Push(&acc)
+	assert.Equal(t, uint64(0), acc.NMetrics())
+}
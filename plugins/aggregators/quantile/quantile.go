@@ -0,0 +1,141 @@
+package quantile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Quantile estimates quantiles of the fields of each series with a t-digest,
+// whose memory use is bounded by the compression.
+type Quantile struct {
+	Quantiles   []float64
+	Compression float64
+
+	cache map[uint64]aggregate
+}
+
+func NewQuantile() telegraf.Aggregator {
+	q := &Quantile{
+		Quantiles:   []float64{0.25, 0.5, 0.75},
+		Compression: 100,
+	}
+	q.Reset()
+	return q
+}
+
+type aggregate struct {
+	fields map[string]*tdigest
+	name   string
+	tags   map[string]string
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output, in the range [0, 1]. The field of quantile 0.99
+  ## of the field "latency" is "latency_p99".
+  # quantiles = [0.25, 0.5, 0.75]
+
+  ## Compression of the t-digest of each field, the number of values kept
+  ## for each field is in the order of the compression. Higher values
+  ## increase the accuracy and the memory use.
+  # compression = 100.0
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+// Init validates the quantiles and compression.
+func (q *Quantile) Init() error {
+	if q.Compression < 1 {
+		return fmt.Errorf("compression must be at least 1, got %v", q.Compression)
+	}
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("quantile %v is not in the range [0, 1]", quantile)
+		}
+	}
+	return nil
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*tdigest),
+		}
+		q.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		if fv, ok := convert(v); ok {
+			digest, ok := a.fields[k]
+			if !ok {
+				digest = newTDigest(q.Compression)
+				a.fields[k] = digest
+			}
+			digest.add(fv)
+		}
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, aggregate := range q.cache {
+		fields := map[string]interface{}{}
+		for k, digest := range aggregate.fields {
+			for _, quantile := range q.Quantiles {
+				fields[k+"_"+suffix(quantile)] = digest.quantile(quantile)
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(aggregate.name, fields, aggregate.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+// suffix returns the suffix of the fields of quantile, the percentile after
+// a "p", such as "p50" or "p99_9".
+func suffix(quantile float64) string {
+	percentile := strconv.FormatFloat(quantile*100, 'g', 10, 64)
+	return "p" + strings.Replace(percentile, ".", "_", 1)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
@@ -0,0 +1,104 @@
+package quantile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var hostB, _ = metric.New("http",
+	map[string]string{"host": "b"},
+	map[string]interface{}{
+		"latency": int64(7),
+	},
+	time.Now(),
+)
+var m1, _ = metric.New("http",
+	map[string]string{},
+	map[string]interface{}{
+		"latency": float64(1),
+	},
+	time.Now(),
+)
+var m2, _ = metric.New("http",
+	map[string]string{},
+	map[string]interface{}{
+		"latency": float64(3),
+	},
+	time.Now(),
+)
+
+func TestQuantilePush(t *testing.T) {
+	q := &Quantile{
+		Quantiles:   []float64{0, 0.5, 0.999, 1},
+		Compression: 100,
+	}
+	require.NoError(t, q.Init())
+	q.Reset()
+
+	for i := 1; i <= 101; i++ {
+		m, _ := metric.New("http",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"latency": float64(i),
+				"code":    int64(200),
+				"status":  "ok",
+			},
+			time.Now(),
+		)
+		q.Add(m)
+	}
+	q.Add(hostB)
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "http", map[string]interface{}{
+		"latency_p0":    float64(1),
+		"latency_p50":   float64(51),
+		"latency_p99_9": float64(101),
+		"latency_p100":  float64(101),
+		"code_p0":       float64(200),
+		"code_p50":      float64(200),
+		"code_p99_9":    float64(200),
+		"code_p100":     float64(200),
+	}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "http", map[string]interface{}{
+		"latency_p0":    float64(7),
+		"latency_p50":   float64(7),
+		"latency_p99_9": float64(7),
+		"latency_p100":  float64(7),
+	}, map[string]string{"host": "b"})
+}
+
+func TestQuantileReset(t *testing.T) {
+	q := &Quantile{
+		Quantiles:   []float64{0.25, 0.5, 0.75},
+		Compression: 100,
+	}
+	q.Reset()
+	q.Add(m1)
+	q.Reset()
+	q.Add(m2)
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	acc.AssertContainsFields(t, "http", map[string]interface{}{
+		"latency_p25": float64(3),
+		"latency_p50": float64(3),
+		"latency_p75": float64(3),
+	})
+}
+
+func TestQuantileInit(t *testing.T) {
+	q := &Quantile{Quantiles: []float64{0.5, 1.5}, Compression: 100}
+	assert.Error(t, q.Init())
+
+	q = &Quantile{Quantiles: []float64{0.5}, Compression: 0}
+	assert.Error(t, q.Init())
+}
@@ -0,0 +1,129 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// centroid is the mean of count values.
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// tdigest is a merging t-digest, a sketch of the distribution of values which
+// estimates quantiles with a better accuracy at the tails. Values are
+// buffered, and merged into centroids whose size is bounded by the scale
+// function k, so that the number of centroids is in the order of the
+// compression whatever the number of values.
+//
+// See Ted Dunning, Computing Extremely Accurate Quantiles Using t-Digests.
+type tdigest struct {
+	compression float64
+
+	centroids []centroid
+	buffer    []centroid
+	count     float64
+	min       float64
+	max       float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// add adds a value to the digest.
+func (t *tdigest) add(x float64) {
+	if math.IsNaN(x) {
+		return
+	}
+	t.buffer = append(t.buffer, centroid{mean: x, count: 1})
+	t.count++
+	if x < t.min {
+		t.min = x
+	}
+	if x > t.max {
+		t.max = x
+	}
+	if float64(len(t.buffer)) >= 4*t.compression {
+		t.merge()
+	}
+}
+
+// merge merges the buffered values into the centroids. Neighbouring
+// centroids are merged as long as the centroid spans at most 1 in k.
+func (t *tdigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].mean < all[j].mean
+	})
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	var before float64
+	kLeft := t.k(0)
+	for _, c := range all[1:] {
+		if t.k((before+cur.count+c.count)/t.count)-kLeft <= 1 {
+			cur.count += c.count
+			cur.mean += (c.mean - cur.mean) * c.count / cur.count
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.count
+		kLeft = t.k(before / t.count)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// k is the scale function k1 of the t-digest, which keeps the centroids
+// small near the extreme quantiles.
+func (t *tdigest) k(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// quantile estimates the value at quantile q, in [0, 1], by interpolating
+// between the centers of the centroids and the extreme values.
+func (t *tdigest) quantile(q float64) float64 {
+	t.merge()
+	if len(t.centroids) == 0 {
+		return math.NaN()
+	}
+	switch {
+	case q <= 0:
+		return t.min
+	case q >= 1:
+		return t.max
+	case len(t.centroids) == 1:
+		return t.centroids[0].mean
+	}
+
+	target := q * t.count
+	first := t.centroids[0]
+	if target < first.count/2 {
+		return t.min + (first.mean-t.min)*target/(first.count/2)
+	}
+
+	var before float64
+	for i := 0; i < len(t.centroids)-1; i++ {
+		c, next := t.centroids[i], t.centroids[i+1]
+		left := before + c.count/2
+		right := before + c.count + next.count/2
+		if target <= right {
+			return c.mean + (next.mean-c.mean)*(target-left)/(right-left)
+		}
+		before += c.count
+	}
+
+	last := t.centroids[len(t.centroids)-1]
+	left := t.count - last.count/2
+	return math.Min(t.max, last.mean+(t.max-last.mean)*(target-left)/(last.count/2))
+}
@@ -0,0 +1,50 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTDigestExact(t *testing.T) {
+	digest := newTDigest(100)
+	for i := 1; i <= 10; i++ {
+		digest.add(float64(i))
+	}
+
+	assert.Equal(t, float64(1), digest.quantile(0))
+	assert.Equal(t, float64(10), digest.quantile(1))
+	assert.Equal(t, 5.5, digest.quantile(0.5))
+}
+
+func TestTDigestBoundedAccuracy(t *testing.T) {
+	digest := newTDigest(100)
+	r := rand.New(rand.NewSource(42))
+	values := make([]float64, 100000)
+	for i := range values {
+		// skewed values, as latencies are
+		values[i] = r.ExpFloat64()
+		digest.add(values[i])
+	}
+	sort.Float64s(values)
+
+	digest.merge()
+	assert.True(t, len(digest.centroids) <= 200, "%d centroids", len(digest.centroids))
+
+	// the error on the rank of the estimates is smaller at the tails
+	for _, q := range []float64{0.001, 0.01, 0.25, 0.5, 0.75, 0.99, 0.999} {
+		rank := float64(sort.SearchFloat64s(values, digest.quantile(q))) / float64(len(values))
+		assert.InDelta(t, q, rank, 0.01*math.Sqrt(q*(1-q)), "quantile %v", q)
+	}
+}
+
+func TestTDigestEmpty(t *testing.T) {
+	digest := newTDigest(100)
+	assert.True(t, math.IsNaN(digest.quantile(0.5)))
+
+	digest.add(3)
+	assert.Equal(t, float64(3), digest.quantile(0.5))
+}
@@ -0,0 +1,246 @@
+package rate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Rate turns counters into per-second rates or deltas. It keeps the last
+// sample of each field of each series across periods, so that the rate of a
+// period covers the time since the last sample of the previous one.
+type Rate struct {
+	Fields     []string          `toml:"fields"`
+	Outputs    []string          `toml:"outputs"`
+	CounterMax float64           `toml:"counter_max"`
+	Expire     internal.Duration `toml:"expire"`
+
+	fieldFilter filter.Filter
+	rate        bool
+	delta       bool
+	series      map[uint64]*series
+	now         func() time.Time
+}
+
+// series holds the counters of a series, and the time of its last sample.
+type series struct {
+	name     string
+	tags     map[string]string
+	counters map[string]*counter
+	lastSeen time.Time
+}
+
+// counter holds the last sample of a field, and the increase and elapsed
+// time summed over the current period.
+type counter struct {
+	value   interface{}
+	time    time.Time
+	delta   float64
+	elapsed time.Duration
+	updated bool
+}
+
+func NewRate() telegraf.Aggregator {
+	r := &Rate{
+		Outputs: []string{"rate"},
+		Expire:  internal.Duration{Duration: 10 * time.Minute},
+		rate:    true,
+		series:  make(map[uint64]*series),
+		now:     time.Now,
+	}
+	return r
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields of the counters, glob patterns are supported. All the numeric
+  ## fields are counters when empty.
+  # fields = ["bytes_*", "packets_*"]
+
+  ## Outputs for each counter, "rate" for the field_rate per second and
+  ## "delta" for the field_delta increase over the period.
+  # outputs = ["rate"]
+
+  ## A counter decreasing is reset, and starts again from 0. When set, a
+  ## counter decreasing from the upper half of counter_max wraps around at
+  ## counter_max instead, e.g. 4294967295 for 32 bits counters.
+  # counter_max = 0.0
+
+  ## Series which do not report for this long are forgotten.
+  # expire = "10m"
+`
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Turn counters into per-second rates or deltas."
+}
+
+// Init compiles the field filter and validates the outputs.
+func (r *Rate) Init() error {
+	var err error
+	r.fieldFilter, err = filter.Compile(r.Fields)
+	if err != nil {
+		return err
+	}
+
+	r.rate, r.delta = false, false
+	for _, output := range r.Outputs {
+		switch output {
+		case "rate":
+			r.rate = true
+		case "delta":
+			r.delta = true
+		default:
+			return fmt.Errorf("invalid output %q, must be rate or delta", output)
+		}
+	}
+	return nil
+}
+
+func (r *Rate) Add(in telegraf.Metric) {
+	id := in.HashID()
+	s, ok := r.series[id]
+	if !ok {
+		s = &series{
+			name:     in.Name(),
+			tags:     in.Tags(),
+			counters: make(map[string]*counter),
+		}
+		r.series[id] = s
+	}
+
+	t := in.Time()
+	if t.After(s.lastSeen) {
+		s.lastSeen = t
+	}
+
+	for k, v := range in.Fields() {
+		if r.fieldFilter != nil && !r.fieldFilter.Match(k) {
+			continue
+		}
+		if _, ok := convert(v); !ok {
+			continue
+		}
+
+		c, ok := s.counters[k]
+		if !ok {
+			s.counters[k] = &counter{value: v, time: t}
+			continue
+		}
+		// samples older than the last one are out of order
+		if !t.After(c.time) {
+			continue
+		}
+		c.delta += r.increase(c.value, v)
+		c.elapsed += t.Sub(c.time)
+		c.updated = true
+		c.value = v
+		c.time = t
+	}
+}
+
+func (r *Rate) Push(acc telegraf.Accumulator) {
+	for _, s := range r.series {
+		fields := map[string]interface{}{}
+		for k, c := range s.counters {
+			if !c.updated {
+				continue
+			}
+			if r.rate {
+				fields[k+"_rate"] = c.delta / c.elapsed.Seconds()
+			}
+			if r.delta {
+				fields[k+"_delta"] = c.delta
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset starts a new period, the last samples are kept, except for the
+// series which have expired.
+func (r *Rate) Reset() {
+	expiry := r.now().Add(-r.Expire.Duration)
+	for id, s := range r.series {
+		if r.Expire.Duration > 0 && s.lastSeen.Before(expiry) {
+			delete(r.series, id)
+			continue
+		}
+		for _, c := range s.counters {
+			c.delta = 0
+			c.elapsed = 0
+			c.updated = false
+		}
+	}
+}
+
+// increase returns the increase of a counter from prev to cur, taking
+// decreases as a reset or a wrap around.
+func (r *Rate) increase(prev, cur interface{}) float64 {
+	d := difference(prev, cur)
+	if d >= 0 {
+		return d
+	}
+
+	p, _ := convert(prev)
+	c, _ := convert(cur)
+	if r.CounterMax > 0 && p >= r.CounterMax/2 && p <= r.CounterMax {
+		return r.CounterMax - p + 1 + c
+	}
+	return c
+}
+
+// difference returns cur - prev, computed with the type of the values when
+// they have the same, so that large counters do not lose precision.
+func difference(prev, cur interface{}) float64 {
+	switch p := prev.(type) {
+	case int64:
+		if c, ok := cur.(int64); ok {
+			return float64(c - p)
+		}
+	case uint64:
+		if c, ok := cur.(uint64); ok {
+			if c >= p {
+				return float64(c - p)
+			}
+			return -float64(p - c)
+		}
+	}
+	p, _ := convert(prev)
+	c, _ := convert(cur)
+	return c - p
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("rate", func() telegraf.Aggregator {
+		return NewRate()
+	})
+}
@@ -0,0 +1,184 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var start = time.Date(2017, time.December, 10, 12, 0, 0, 0, time.UTC)
+
+var m1, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{
+		"bytes_recv": int64(100),
+		"bytes_sent": uint64(10),
+		"drop_in":    int64(1),
+	},
+	start,
+)
+var m2, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{
+		"bytes_recv": int64(300),
+		"bytes_sent": uint64(30),
+		"drop_in":    int64(5),
+	},
+	start.Add(10*time.Second),
+)
+var m3, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{
+		"bytes_recv": int64(700),
+		"bytes_sent": uint64(50),
+		"drop_in":    int64(5),
+	},
+	start.Add(20*time.Second),
+)
+var m4, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{
+		"bytes_recv": int64(1200),
+	},
+	start.Add(30*time.Second),
+)
+
+var wrap1, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{"bytes_recv": int64(4294967000)},
+	start,
+)
+var wrap2, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{"bytes_recv": int64(100)},
+	start.Add(10*time.Second),
+)
+var wrap3, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{"bytes_recv": int64(200)},
+	start.Add(20*time.Second),
+)
+var wrap4, _ = metric.New("net",
+	map[string]string{"interface": "eth0"},
+	map[string]interface{}{"bytes_recv": int64(50)},
+	start.Add(30*time.Second),
+)
+
+func TestRateAcrossPeriods(t *testing.T) {
+	r := &Rate{
+		Fields:  []string{"bytes_*"},
+		Outputs: []string{"rate", "delta"},
+		Expire:  internal.Duration{Duration: 10 * time.Minute},
+		series:  make(map[uint64]*series),
+		now:     func() time.Time { return start },
+	}
+	require.NoError(t, r.Init())
+
+	r.Add(m1)
+	r.Add(m2)
+	r.Add(m3)
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	r.Reset()
+	acc.AssertContainsTaggedFields(t, "net", map[string]interface{}{
+		"bytes_recv_rate":  float64(30),
+		"bytes_recv_delta": float64(600),
+		"bytes_sent_rate":  float64(2),
+		"bytes_sent_delta": float64(40),
+	}, map[string]string{"interface": "eth0"})
+
+	// the rate of the next period starts from the last sample
+	r.Add(m4)
+	acc.ClearMetrics()
+	r.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "net", map[string]interface{}{
+		"bytes_recv_rate":  float64(50),
+		"bytes_recv_delta": float64(500),
+	}, map[string]string{"interface": "eth0"})
+}
+
+func TestRateNeedsTwoSamples(t *testing.T) {
+	r := &Rate{
+		Outputs: []string{"rate"},
+		Expire:  internal.Duration{Duration: 10 * time.Minute},
+		series:  make(map[uint64]*series),
+		now:     func() time.Time { return start },
+	}
+	require.NoError(t, r.Init())
+
+	r.Add(m2)
+	// out of order samples are ignored
+	r.Add(m1)
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestRateResetAndWrap(t *testing.T) {
+	r := &Rate{
+		Outputs: []string{"delta"},
+		Expire:  internal.Duration{Duration: 10 * time.Minute},
+		series:  make(map[uint64]*series),
+		now:     func() time.Time { return start },
+	}
+	require.NoError(t, r.Init())
+	r.Add(wrap1)
+	r.Add(wrap2)
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	// without counter_max, a decrease is a reset
+	acc.AssertContainsFields(t, "net", map[string]interface{}{
+		"bytes_recv_delta": float64(100),
+	})
+
+	r = &Rate{
+		Outputs:    []string{"delta"},
+		CounterMax: 4294967295,
+		Expire:     internal.Duration{Duration: 10 * time.Minute},
+		series:     make(map[uint64]*series),
+		now:        func() time.Time { return start },
+	}
+	require.NoError(t, r.Init())
+	r.Add(wrap1)
+	r.Add(wrap2)
+	r.Add(wrap3)
+	// a decrease from the lower half is a reset
+	r.Add(wrap4)
+
+	acc.ClearMetrics()
+	r.Push(&acc)
+	acc.AssertContainsFields(t, "net", map[string]interface{}{
+		"bytes_recv_delta": float64(296 + 100 + 100 + 50),
+	})
+}
+
+func TestRateExpire(t *testing.T) {
+	r := &Rate{
+		Outputs: []string{"rate"},
+		Expire:  internal.Duration{Duration: time.Minute},
+		series:  make(map[uint64]*series),
+		now:     func() time.Time { return start },
+	}
+	require.NoError(t, r.Init())
+	r.Add(m1)
+	r.Reset()
+	assert.Len(t, r.series, 1)
+
+	r.now = func() time.Time { return start.Add(2 * time.Minute) }
+	r.Reset()
+	assert.Len(t, r.series, 0)
+}
+
+func TestRateInit(t *testing.T) {
+	r := &Rate{Outputs: []string{"derivative"}}
+	assert.Error(t, r.Init())
+}
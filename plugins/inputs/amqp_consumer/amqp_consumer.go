@@ -26,7 +26,9 @@ type AMQPConsumer struct {
 	BindingKey string `toml:"binding_key"`
 
 	// Controls how many messages the server will try to keep on the network
-	// for consumers before receiving delivery acks.
+	// for consumers before receiving delivery acks. It is raised to
+	// MaxUndeliveredMessages if lower, as messages are only acked once
+	// delivered.
 	PrefetchCount int
 
 	// AMQP Auth method
@@ -40,6 +42,10 @@ type AMQPConsumer struct {
 	// Use SSL but skip chain & host verification
 	InsecureSkipVerify bool
 
+	// Maximum number of messages whose metrics may be pending delivery to
+	// the outputs at once.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 	conn   *amqp.Connection
 	wg     *sync.WaitGroup
@@ -55,8 +61,9 @@ func (a *externalAuth) Response() string {
 }
 
 const (
-	DefaultAuthMethod    = "PLAIN"
-	DefaultPrefetchCount = 50
+	DefaultAuthMethod             = "PLAIN"
+	DefaultPrefetchCount          = 50
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -70,9 +77,17 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Binding Key
   binding_key = "#"
 
-  ## Maximum number of messages server should give to the worker.
+  ## Maximum number of messages server should give to the worker. It is
+  ## raised to max_undelivered_messages if lower.
   prefetch_count = 50
 
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Messages are only acknowledged once all their metrics are
+  ## written; undelivered messages are requeued. As the server stops sending
+  ## once prefetch_count messages are unacknowledged, prefetch_count is never
+  ## lower than this limit.
+  # max_undelivered_messages = 1000
+
   ## Auth method. PLAIN and EXTERNAL are supported
   ## Using EXTERNAL requires enabling the rabbitmq_auth_mechanism_ssl plugin as
   ## described here: https://www.rabbitmq.com/plugins.html
@@ -135,14 +150,23 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	if a.MaxUndeliveredMessages <= 0 {
+		a.MaxUndeliveredMessages = DefaultMaxUndeliveredMessages
+	}
+	if a.PrefetchCount < a.MaxUndeliveredMessages {
+		a.PrefetchCount = a.MaxUndeliveredMessages
+	}
+
 	msgs, err := a.connect(amqpConf)
 	if err != nil {
 		return err
 	}
 
+	tacc := acc.WithTracking(a.MaxUndeliveredMessages)
+
 	a.wg = &sync.WaitGroup{}
 	a.wg.Add(1)
-	go a.process(msgs, acc)
+	go a.process(msgs, tacc)
 
 	go func() {
 		err := <-a.conn.NotifyClose(make(chan *amqp.Error))
@@ -160,7 +184,7 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 			}
 
 			a.wg.Add(1)
-			go a.process(msgs, acc)
+			go a.process(msgs, tacc)
 			break
 		}
 	}()
@@ -242,22 +266,69 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
-func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
+// Read messages from queue and add them to the Accumulator.
+//
+// Messages are acknowledged once their metrics have been written by the
+// outputs, at most MaxUndeliveredMessages are pending at any time.
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.TrackingAccumulator) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+
+	sem := make(chan struct{}, a.MaxUndeliveredMessages)
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		select {
+		case info := <-acc.Delivered():
+			if a.onDelivery(info, undelivered) {
+				<-sem
+			}
+		case sem <- struct{}{}:
+			select {
+			case info := <-acc.Delivered():
+				if a.onDelivery(info, undelivered) {
+					<-sem
+				}
+				<-sem
+			case d, ok := <-msgs:
+				if !ok {
+					log.Printf("I! AMQP consumer queue closed")
+					return
+				}
+				metrics, err := a.parser.Parse(d.Body)
+				if err != nil {
+					log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
+					d.Ack(false)
+					<-sem
+					continue
+				}
+				id := acc.AddTrackingMetricGroup(metrics)
+				undelivered[id] = d
 			}
 		}
+	}
+}
 
-		d.Ack(false)
+// onDelivery acknowledges the message the delivered metrics came from, or
+// requeues it if they could not be written. It returns false if the message
+// is not one of undelivered.
+func (a *AMQPConsumer) onDelivery(info telegraf.DeliveryInfo, undelivered map[telegraf.TrackingID]amqp.Delivery) bool {
+	d, ok := undelivered[info.ID()]
+	if !ok {
+		// the message was received on a previous connection, the server
+		// will redeliver it
+		return false
+	}
+	delete(undelivered, info.ID())
+
+	var err error
+	if info.Delivered() {
+		err = d.Ack(false)
+	} else {
+		err = d.Reject(true)
+	}
+	if err != nil {
+		log.Printf("E! Unable to acknowledge AMQP message: %s", err)
 	}
-	log.Printf("I! AMQP consumer queue closed")
+	return true
 }
 
 func (a *AMQPConsumer) Stop() {
@@ -273,8 +344,9 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			PrefetchCount:          DefaultPrefetchCount,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
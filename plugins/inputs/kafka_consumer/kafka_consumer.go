@@ -45,6 +45,10 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	// MaxUndeliveredMessages is the maximum number of messages whose
+	// metrics may be pending delivery to the outputs at once.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
@@ -54,7 +58,12 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+
+	// messages pending delivery, by tracking id
+	undelivered map[telegraf.TrackingID]*sarama.ConsumerMessage
+	// offsets tracks the offsets which can be committed
+	offsets *offsetTracker
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
@@ -92,6 +101,12 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. The offset of a partition is only committed up to the
+  ## messages whose metrics are all written, so that no messages are lost on
+  ## restart. Consumption is paused when this limit is reached.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -111,7 +126,10 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var clusterErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages <= 0 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 
 	config := cluster.NewConfig()
 	config.Consumer.Return.Errors = true
@@ -175,39 +193,159 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 
 // receiver() reads all incoming messages from the consumer, and parses them into
 // influxdb metric points.
+//
+// A slot of the sem channel is taken for every message whose metrics are
+// pending delivery, including the messages added again, once it is full no
+// more messages are read until the outputs catch up.
 func (k *Kafka) receiver() {
+	sem := make(chan struct{}, k.MaxUndeliveredMessages)
+	k.undelivered = make(map[telegraf.TrackingID]*sarama.ConsumerMessage)
+	k.offsets = newOffsetTracker()
 	for {
 		select {
 		case <-k.done:
 			return
-		case err := <-k.errs:
-			if err != nil {
-				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
+		case info := <-k.acc.Delivered():
+			if k.onDelivery(info) {
+				<-sem
 			}
-		case msg := <-k.in:
-			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
-				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
-					len(msg.Value), k.MaxMessageLen))
-			} else {
-				metrics, err := k.parser.Parse(msg.Value)
+		case sem <- struct{}{}:
+			select {
+			case <-k.done:
+				return
+			case info := <-k.acc.Delivered():
+				if k.onDelivery(info) {
+					<-sem
+				}
+				<-sem
+			case err := <-k.errs:
 				if err != nil {
-					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
-						string(msg.Value), err.Error()))
+					k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
 				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+				<-sem
+			case msg := <-k.in:
+				k.offsets.read(msg)
+				if !k.onMessage(msg) {
+					k.markOffset(k.offsets.done(msg))
+					<-sem
 				}
 			}
+		}
+	}
+}
 
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Cluster.MarkOffset(msg, "")
-				k.Unlock()
-			}
+// onMessage parses msg and adds its metrics to the accumulator, returning
+// false if the message could not be added and is done with.
+func (k *Kafka) onMessage(msg *sarama.ConsumerMessage) bool {
+	if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
+		k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
+			len(msg.Value), k.MaxMessageLen))
+		return false
+	}
+
+	metrics, err := k.parser.Parse(msg.Value)
+	if err != nil {
+		k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
+			string(msg.Value), err.Error()))
+	}
+	id := k.acc.AddTrackingMetricGroup(metrics)
+	k.undelivered[id] = msg
+	return true
+}
+
+// onDelivery commits the offset of a message once all its metrics have been
+// written, and the messages before it in its partition are done with. A
+// message that could not be delivered is added again, it stays pending in
+// the meantime so that the offset of its partition is committed up to the
+// message before it only. It returns true if the message is done with, and
+// false if it is still pending or is not one of the undelivered messages.
+func (k *Kafka) onDelivery(info telegraf.DeliveryInfo) bool {
+	msg, ok := k.undelivered[info.ID()]
+	if !ok {
+		return false
+	}
+	delete(k.undelivered, info.ID())
+
+	if !info.Delivered() {
+		k.acc.AddError(fmt.Errorf("Metrics from message at offset %d of "+
+			"partition %d were not delivered, adding them again",
+			msg.Offset, msg.Partition))
+		if k.onMessage(msg) {
+			return false
 		}
 	}
+	k.markOffset(k.offsets.done(msg))
+	return true
+}
+
+func (k *Kafka) markOffset(msg *sarama.ConsumerMessage) {
+	if msg == nil || k.doNotCommitMsgs {
+		return
+	}
+	// TODO(cam) this locking can be removed if this PR gets merged:
+	// https://github.com/wvanbergen/kafka/pull/84
+	k.Lock()
+	k.Cluster.MarkOffset(msg, "")
+	k.Unlock()
+}
+
+// offsetTracker tracks the messages read from each partition. Offsets are
+// cumulative, committing the offset of a message commits all the messages
+// before it in its partition, so the offset committed is the one of the last
+// message before the first which is not done with.
+type offsetTracker struct {
+	partitions map[topicPartition]*partitionOffsets
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type partitionOffsets struct {
+	// pending are the messages read and not committed yet, in order.
+	pending []*sarama.ConsumerMessage
+	// done are the offsets of the pending messages done with.
+	done map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+func (t *offsetTracker) partition(msg *sarama.ConsumerMessage) *partitionOffsets {
+	key := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+	return p
+}
+
+// read records that msg was read, messages are read in order within a
+// partition.
+func (t *offsetTracker) read(msg *sarama.ConsumerMessage) {
+	p := t.partition(msg)
+	p.pending = append(p.pending, msg)
+}
+
+// done records that msg is done with, and returns the last message whose
+// offset can be committed, or nil if there is none.
+func (t *offsetTracker) done(msg *sarama.ConsumerMessage) *sarama.ConsumerMessage {
+	p := t.partition(msg)
+	p.done[msg.Offset] = true
+
+	var commit *sarama.ConsumerMessage
+	for len(p.pending) > 0 && p.done[p.pending[0].Offset] {
+		commit = p.pending[0]
+		delete(p.done, commit.Offset)
+		p.pending[0] = nil
+		p.pending = p.pending[1:]
+	}
+	return commit
 }
 
 func (k *Kafka) Stop() {
@@ -223,8 +361,12 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
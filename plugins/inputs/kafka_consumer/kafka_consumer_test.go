@@ -1,9 +1,13 @@
 package kafka_consumer
 
 import (
+	"math"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -29,6 +33,8 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan error, 1000),
 		done:            make(chan struct{}),
+
+		MaxUndeliveredMessages: 1000,
 	}
 	return &k, in
 }
@@ -37,7 +43,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 func TestRunParser(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -52,7 +58,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -69,7 +75,7 @@ func TestDropOverlongMsg(t *testing.T) {
 	k, in := newTestKafka()
 	k.MaxMessageLen = maxMessageLen
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 	overlongMsg := strings.Repeat("v", maxMessageLen+1)
 
@@ -84,7 +90,7 @@ func TestDropOverlongMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -103,7 +109,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -122,7 +128,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewJSONParser("kafka_json_test", []string{}, nil)
@@ -140,6 +146,154 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+func TestOffsetTracker(t *testing.T) {
+	msg := func(partition int32, offset int64) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{
+			Topic:     "telegraf",
+			Partition: partition,
+			Offset:    offset,
+		}
+	}
+	offsets := newOffsetTracker()
+	for _, m := range []*sarama.ConsumerMessage{
+		msg(0, 1), msg(0, 2), msg(0, 4), msg(1, 1), msg(1, 2),
+	} {
+		offsets.read(m)
+	}
+
+	// the offset is committed up to the first pending message
+	assert.Nil(t, offsets.done(msg(0, 2)))
+	assert.Equal(t, msg(0, 2), offsets.done(msg(0, 1)))
+	assert.Equal(t, msg(0, 4), offsets.done(msg(0, 4)))
+
+	// a message added again holds back the commits of its partition only
+	assert.Nil(t, offsets.done(msg(1, 2)))
+	offsets.read(msg(0, 5))
+	assert.Equal(t, msg(0, 5), offsets.done(msg(0, 5)))
+	assert.Equal(t, msg(1, 2), offsets.done(msg(1, 1)))
+}
+
+// rejectingAccumulator reports the first reject metric groups added as not
+// delivered, and the others as delivered. It records the largest number of
+// messages of k pending at once.
+type rejectingAccumulator struct {
+	telegraf.TrackingAccumulator
+	acc       *testutil.Accumulator
+	delivered chan telegraf.DeliveryInfo
+	reject    int
+	lastID    telegraf.TrackingID
+
+	k          *Kafka
+	mu         sync.Mutex
+	maxPending int
+}
+
+func (a *rejectingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.mu.Lock()
+	// the group is added to the undelivered messages once it is returned
+	if pending := len(a.k.undelivered) + 1; pending > a.maxPending {
+		a.maxPending = pending
+	}
+	a.mu.Unlock()
+
+	a.acc.AddMetrics(group)
+	a.lastID++
+	a.delivered <- &deliveryInfo{id: a.lastID, delivered: a.reject <= 0}
+	a.reject--
+	return a.lastID
+}
+
+func (a *rejectingAccumulator) MaxPending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.maxPending
+}
+
+func (a *rejectingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
+// Test that a message whose metrics were not delivered is added again, and
+// that the messages after it are still consumed
+func TestRedeliverRejectedMsg(t *testing.T) {
+	k, in := newTestKafka()
+	acc := testutil.Accumulator{}
+	k.acc = &rejectingAccumulator{
+		TrackingAccumulator: acc.WithTracking(k.MaxUndeliveredMessages),
+		acc:                 &acc,
+		delivered:           make(chan telegraf.DeliveryInfo, k.MaxUndeliveredMessages),
+		reject:              1,
+		k:                   k,
+	}
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	for offset := int64(0); offset < 3; offset++ {
+		msg := saramaMsg(testMsg)
+		msg.Offset = offset
+		in <- msg
+	}
+	acc.Wait(4)
+	acc.WaitError(1)
+
+	assert.Equal(t, uint64(4), acc.NMetrics())
+}
+
+// Test that messages added again because they are rejected repeatedly keep
+// their slot, so that no more than max_undelivered_messages are pending
+func TestRedeliverRejectedMsgBounded(t *testing.T) {
+	k, in := newTestKafka()
+	k.MaxUndeliveredMessages = 2
+	acc := testutil.Accumulator{}
+	racc := &rejectingAccumulator{
+		TrackingAccumulator: acc.WithTracking(k.MaxUndeliveredMessages),
+		acc:                 &acc,
+		// larger than the limit, so that pending too many messages fails
+		// the test instead of blocking the receiver
+		delivered: make(chan telegraf.DeliveryInfo, 100),
+		reject:    math.MaxInt32,
+		k:         k,
+	}
+	k.acc = racc
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	for offset := int64(0); offset < 5; offset++ {
+		msg := saramaMsg(testMsg)
+		msg.Offset = offset
+		in <- msg
+	}
+	waited := make(chan struct{})
+	go func() {
+		acc.Wait(50)
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rejected messages are not added again")
+	}
+
+	assert.True(t, racc.MaxPending() <= k.MaxUndeliveredMessages,
+		"%d messages pending", racc.MaxPending())
+	assert.Equal(t, 3, len(in))
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
@@ -18,6 +18,8 @@ import (
 // 30 Seconds is the default used by paho.mqtt.golang
 var defaultConnectionTimeout = internal.Duration{Duration: 30 * time.Second}
 
+const defaultMaxUndeliveredMessages = 1000
+
 type MQTTConsumer struct {
 	Servers           []string
 	Topics            []string
@@ -31,6 +33,10 @@ type MQTTConsumer struct {
 	// Legacy metric buffer support
 	MetricBuffer int
 
+	// Maximum number of messages whose metrics may be pending delivery to
+	// the outputs at once.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	PersistentSession bool
 	ClientID          string `toml:"client_id"`
 
@@ -50,7 +56,7 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	connected bool
 }
@@ -65,6 +71,10 @@ var sampleConfig = `
   ## Connection timeout for initial connection in seconds
   connection_timeout = "30s"
 
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Consumption is paused when this limit is reached.
+  # max_undelivered_messages = 1000
+
   ## Topics to subscribe to
   topics = [
     "telegraf/host01/cpu",
@@ -119,7 +129,10 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages <= 0 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -180,23 +193,37 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 
 // receiver() reads all incoming messages from the consumer, and parses them into
 // influxdb metric points.
+//
+// Messages are acknowledged to the broker by the client library as soon as
+// they are received, so delivery tracking is only used to stop reading while
+// MaxUndeliveredMessages messages are pending.
 func (m *MQTTConsumer) receiver() {
+	sem := make(chan struct{}, m.MaxUndeliveredMessages)
 	for {
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
-			topic := msg.Topic()
-			metrics, err := m.parser.Parse(msg.Payload())
-			if err != nil {
-				m.acc.AddError(fmt.Errorf("E! MQTT Parse Error\nmessage: %s\nerror: %s",
-					string(msg.Payload()), err.Error()))
-			}
-
-			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+		case <-m.acc.Delivered():
+			<-sem
+		case sem <- struct{}{}:
+			select {
+			case <-m.done:
+				return
+			case <-m.acc.Delivered():
+				<-sem
+				<-sem
+			case msg := <-m.in:
+				topic := msg.Topic()
+				metrics, err := m.parser.Parse(msg.Payload())
+				if err != nil {
+					m.acc.AddError(fmt.Errorf("E! MQTT Parse Error\nmessage: %s\nerror: %s",
+						string(msg.Payload()), err.Error()))
+				}
+
+				for _, metric := range metrics {
+					metric.AddTag("topic", topic)
+				}
+				m.acc.AddTrackingMetricGroup(metrics)
 			}
 		}
 	}
@@ -284,7 +311,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
 		return &MQTTConsumer{
-			ConnectionTimeout: defaultConnectionTimeout,
+			ConnectionTimeout:      defaultConnectionTimeout,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -27,6 +27,8 @@ func newTestMQTTConsumer() (*MQTTConsumer, chan mqtt.Message) {
 		in:        in,
 		done:      make(chan struct{}),
 		connected: true,
+
+		MaxUndeliveredMessages: 1000,
 	}
 
 	return n, in
@@ -81,7 +83,7 @@ func TestPersistentClientIDFail(t *testing.T) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -97,7 +99,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserNegativeNumber(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -114,7 +116,7 @@ func TestRunParserNegativeNumber(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -132,7 +134,7 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	defer close(n.done)
 
@@ -151,7 +153,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -169,7 +171,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
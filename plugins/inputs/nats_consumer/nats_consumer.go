@@ -35,6 +35,10 @@ type natsConsumer struct {
 	// Legacy metric buffer support
 	MetricBuffer int
 
+	// Maximum number of messages whose metrics may be pending delivery to
+	// the outputs at once.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 
 	sync.Mutex
@@ -47,7 +51,7 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
 }
 
 var sampleConfig = `
@@ -65,6 +69,10 @@ var sampleConfig = `
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Consumption is paused when this limit is reached.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -97,7 +105,10 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages <= 0 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	var connectErr error
 
@@ -155,22 +166,36 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 
 // receiver() reads all incoming messages from NATS, and parses them into
 // telegraf metrics.
+//
+// NATS has no acknowledgements, so delivery tracking is only used to stop
+// reading while MaxUndeliveredMessages messages are pending.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+
+	sem := make(chan struct{}, n.MaxUndeliveredMessages)
 	for {
 		select {
 		case <-n.done:
 			return
-		case err := <-n.errs:
-			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
-			metrics, err := n.parser.Parse(msg.Data)
-			if err != nil {
-				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
-			}
-
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+		case <-n.acc.Delivered():
+			<-sem
+		case sem <- struct{}{}:
+			select {
+			case <-n.done:
+				return
+			case <-n.acc.Delivered():
+				<-sem
+				<-sem
+			case err := <-n.errs:
+				n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
+				<-sem
+			case msg := <-n.in:
+				metrics, err := n.parser.Parse(msg.Data)
+				if err != nil {
+					n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
+				}
+
+				n.acc.AddTrackingMetricGroup(metrics)
 			}
 		}
 	}
@@ -201,15 +226,18 @@ func (n *natsConsumer) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 func init() {
 	inputs.Add("nats_consumer", func() telegraf.Input {
 		return &natsConsumer{
-			Servers:             []string{"nats://localhost:4222"},
-			Secure:              false,
-			Subjects:            []string{"telegraf"},
-			QueueGroup:          "telegraf_consumers",
-			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
-			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+			Servers:                []string{"nats://localhost:4222"},
+			Secure:                 false,
+			Subjects:               []string{"telegraf"},
+			QueueGroup:             "telegraf_consumers",
+			PendingBytesLimit:      nats.DefaultSubPendingBytesLimit,
+			PendingMessageLimit:    nats.DefaultSubPendingMsgsLimit,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -27,6 +27,8 @@ func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 		in:         in,
 		errs:       make(chan error, metricBuffer),
 		done:       make(chan struct{}),
+
+		MaxUndeliveredMessages: 1000,
 	}
 	return n, in
 }
@@ -35,7 +37,7 @@ func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -50,7 +52,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -67,7 +69,7 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -86,7 +88,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -105,7 +107,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
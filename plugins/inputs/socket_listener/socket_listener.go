@@ -2,6 +2,7 @@ package socket_listener
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
@@ -26,6 +27,8 @@ type streamSocketListener struct {
 	net.Listener
 	*SocketListener
 
+	tlsConfig *tls.Config
+
 	connections    map[string]net.Conn
 	connectionsMtx sync.Mutex
 }
@@ -42,6 +45,14 @@ func (ssl *streamSocketListener) listen() {
 			break
 		}
 
+		// keep alive is set on the TCP connection, before it is wrapped
+		if err := ssl.setKeepAlive(c); err != nil {
+			ssl.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", ssl.ServiceAddress, err))
+		}
+		if ssl.tlsConfig != nil {
+			c = tls.Server(c, ssl.tlsConfig)
+		}
+
 		ssl.connectionsMtx.Lock()
 		if ssl.MaxConnections > 0 && len(ssl.connections) >= ssl.MaxConnections {
 			ssl.connectionsMtx.Unlock()
@@ -51,10 +62,6 @@ func (ssl *streamSocketListener) listen() {
 		ssl.connections[c.RemoteAddr().String()] = c
 		ssl.connectionsMtx.Unlock()
 
-		if err := ssl.setKeepAlive(c); err != nil {
-			ssl.AddError(fmt.Errorf("unable to configure keep alive (%s): %s", ssl.ServiceAddress, err))
-		}
-
 		go ssl.read(c)
 	}
 
@@ -93,6 +100,9 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	defer c.Close()
 
 	scnr := bufio.NewScanner(c)
+	if ssl.SplitFunc != nil {
+		scnr.Split(ssl.SplitFunc)
+	}
 	for {
 		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
 			c.SetReadDeadline(time.Now().Add(ssl.ReadTimeout.Duration))
@@ -155,11 +165,26 @@ type SocketListener struct {
 	ReadTimeout     *internal.Duration
 	KeepAlivePeriod *internal.Duration
 
+	TlsAllowedCacerts []string
+	TlsCert           string
+	TlsKey            string
+
+	// SplitFunc splits the data read from stream sockets into the messages
+	// given to the parser, it defaults to bufio.ScanLines. Plugins built on
+	// the socket listener, such as syslog, set it for their framing.
+	SplitFunc bufio.SplitFunc
+
 	parsers.Parser
 	telegraf.Accumulator
 	io.Closer
 }
 
+// splitter is implemented by the parsers of binary data formats, such as
+// protobuf, whose messages are not delimited by newlines in streams.
+type splitter interface {
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+}
+
 func (sl *SocketListener) Description() string {
 	return "Generic socket listener capable of handling multiple socket types."
 }
@@ -200,6 +225,15 @@ func (sl *SocketListener) SampleConfig() string {
   ## Defaults to the OS configuration.
   # keep_alive_period = "5m"
 
+  ## Set the service certificate and key to accept TLS connections.
+  ## Only applies to stream sockets (e.g. TCP).
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -245,9 +279,22 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 			}
 		}
 
+		tlsConfig, err := internal.GetServerTLSConfig(sl.TlsCert, sl.TlsKey, sl.TlsAllowedCacerts)
+		if err != nil {
+			l.Close()
+			return err
+		}
+
+		if sl.SplitFunc == nil {
+			if s, ok := sl.Parser.(splitter); ok {
+				sl.SplitFunc = s.Split
+			}
+		}
+
 		ssl := &streamSocketListener{
 			Listener:       l,
 			SocketListener: sl,
+			tlsConfig:      tlsConfig,
 		}
 
 		sl.Closer = ssl
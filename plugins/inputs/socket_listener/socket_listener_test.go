@@ -6,6 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,6 +30,38 @@ func TestSocketListener_tcp(t *testing.T) {
 	testSocketListener(t, sl, client)
 }
 
+func TestSocketListener_tcpProtobuf(t *testing.T) {
+	sl := newSocketListener()
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Parser = &protobuf.ProtobufParser{}
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	s, err := serializers.NewSerializer(&serializers.Config{DataFormat: "protobuf"})
+	require.NoError(t, err)
+	// the string values hold newlines, which must not split the messages
+	for i, v := range []string{"a\nb", "c\n"} {
+		m, err := metric.New("test", map[string]string{},
+			map[string]interface{}{"v": v}, time.Unix(0, int64(i)), telegraf.Gauge)
+		require.NoError(t, err)
+		buf, err := s.Serialize(m)
+		require.NoError(t, err)
+		client.Write(buf)
+	}
+
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, map[string]interface{}{"v": "a\nb"}, acc.Metrics[0].Fields)
+	assert.Equal(t, map[string]interface{}{"v": "c\n"}, acc.Metrics[1].Fields)
+}
+
 func TestSocketListener_udp(t *testing.T) {
 	sl := newSocketListener()
 	sl.ServiceAddress = "udp://127.0.0.1:0"
@@ -0,0 +1,372 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	measurement = "syslog"
+
+	// rfc3164Stamp is the layout of the timestamps of RFC3164 messages,
+	// which have neither year nor time zone.
+	rfc3164Stamp = "Jan _2 15:04:05"
+)
+
+var (
+	errTruncated = errors.New("truncated syslog message")
+
+	// byteOrderMark may start the MSG part of RFC5424 messages.
+	byteOrderMark = []byte("\xEF\xBB\xBF")
+)
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Parser parses syslog messages, in the RFC5424 format or in the older BSD
+// format described in RFC3164. Each buffer holds a single message.
+type Parser struct {
+	// Separator joins the structured data element ids and parameter names
+	// in the field keys.
+	Separator   string
+	DefaultTags map[string]string
+
+	now func() time.Time
+}
+
+// message holds the parts of a syslog message, the string parts are empty
+// when they are absent or nil.
+type message struct {
+	facility  int
+	severity  int
+	version   int
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procid    string
+	msgid     string
+	sd        []sdElement
+	msg       string
+}
+
+type sdElement struct {
+	id     string
+	params []sdParam
+}
+
+type sdParam struct {
+	name  string
+	value string
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	buf = bytes.TrimRight(buf, "\r\n\x00")
+	if len(buf) == 0 {
+		return []telegraf.Metric{}, nil
+	}
+
+	m, err := p.parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: syslog", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parse(buf []byte) (telegraf.Metric, error) {
+	msg := &message{}
+	rest, err := msg.parsePriority(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version of RFC5424 messages follows the priority, where the
+	// timestamp of RFC3164 messages starts with the name of a month.
+	if len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+		err = msg.parseRFC5424(rest)
+	} else {
+		err = msg.parseRFC3164(rest, p.currentTime())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.metric(msg)
+}
+
+func (p *Parser) metric(msg *message) (telegraf.Metric, error) {
+	tags := map[string]string{
+		"severity": severityNames[msg.severity],
+		"facility": facilityNames[msg.facility],
+	}
+	if msg.hostname != "" {
+		tags["hostname"] = msg.hostname
+	}
+	if msg.appname != "" {
+		tags["appname"] = msg.appname
+	}
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	fields := map[string]interface{}{
+		"severity_code": int64(msg.severity),
+		"facility_code": int64(msg.facility),
+	}
+	if msg.version > 0 {
+		fields["version"] = int64(msg.version)
+	}
+	if msg.procid != "" {
+		fields["procid"] = msg.procid
+	}
+	if msg.msgid != "" {
+		fields["msgid"] = msg.msgid
+	}
+	if msg.msg != "" {
+		fields["message"] = msg.msg
+	}
+	for _, e := range msg.sd {
+		if len(e.params) == 0 {
+			fields[e.id] = true
+			continue
+		}
+		for _, param := range e.params {
+			fields[e.id+p.Separator+param.name] = param.value
+		}
+	}
+
+	t := msg.timestamp
+	if t.IsZero() {
+		t = p.currentTime()
+	}
+	return metric.New(measurement, tags, fields, t)
+}
+
+func (p *Parser) currentTime() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+// parsePriority reads the facility and severity of the <PRI> part and
+// returns the rest of buf.
+func (msg *message) parsePriority(buf []byte) ([]byte, error) {
+	end := bytes.IndexByte(buf, '>')
+	if len(buf) == 0 || buf[0] != '<' || end < 2 || end > 4 {
+		return nil, fmt.Errorf("invalid syslog priority in %q", truncate(buf))
+	}
+	pri, err := strconv.Atoi(string(buf[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, fmt.Errorf("invalid syslog priority %q", buf[1:end])
+	}
+	msg.facility = pri / 8
+	msg.severity = pri % 8
+	return buf[end+1:], nil
+}
+
+// parseRFC5424 reads the part of a RFC5424 message following the priority:
+//
+//	VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+//
+// where "-" stands for a nil value.
+func (msg *message) parseRFC5424(buf []byte) error {
+	var header [6]string
+	for i := range header {
+		sp := bytes.IndexByte(buf, ' ')
+		if sp < 0 {
+			return errTruncated
+		}
+		header[i] = string(buf[:sp])
+		buf = buf[sp+1:]
+	}
+
+	version, err := strconv.Atoi(header[0])
+	if err != nil || version < 1 {
+		return fmt.Errorf("invalid syslog version %q", header[0])
+	}
+	msg.version = version
+
+	if header[1] != "-" {
+		msg.timestamp, err = time.Parse(time.RFC3339Nano, header[1])
+		if err != nil {
+			return fmt.Errorf("invalid syslog timestamp %q", header[1])
+		}
+	}
+	msg.hostname = nilValue(header[2])
+	msg.appname = nilValue(header[3])
+	msg.procid = nilValue(header[4])
+	msg.msgid = nilValue(header[5])
+
+	switch {
+	case len(buf) > 0 && buf[0] == '-':
+		buf = buf[1:]
+	case len(buf) > 0 && buf[0] == '[':
+		buf, err = msg.parseStructuredData(buf)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid syslog structured data in %q", truncate(buf))
+	}
+
+	if len(buf) > 0 {
+		if buf[0] != ' ' {
+			return fmt.Errorf("invalid syslog structured data in %q", truncate(buf))
+		}
+		msg.msg = string(bytes.TrimPrefix(buf[1:], byteOrderMark))
+	}
+	return nil
+}
+
+// parseStructuredData reads the SD-ELEMENTs starting buf:
+//
+//	[SD-ID PARAM-NAME="PARAM-VALUE" ...]
+//
+// where the '"', '\' and ']' characters of the values are escaped with a
+// backslash.
+func (msg *message) parseStructuredData(buf []byte) ([]byte, error) {
+	for len(buf) > 0 && buf[0] == '[' {
+		buf = buf[1:]
+		end := bytes.IndexAny(buf, " ]")
+		if end < 1 {
+			return nil, errTruncated
+		}
+		element := sdElement{id: string(buf[:end])}
+		buf = buf[end:]
+
+		for buf[0] == ' ' {
+			buf = buf[1:]
+			eq := bytes.IndexByte(buf, '=')
+			if eq < 1 || len(buf) < eq+2 || buf[eq+1] != '"' {
+				return nil, fmt.Errorf("invalid syslog structured data parameter in %q", truncate(buf))
+			}
+			param := sdParam{name: string(buf[:eq])}
+			buf = buf[eq+2:]
+
+			var value []byte
+			for {
+				if len(buf) == 0 {
+					return nil, errTruncated
+				}
+				c := buf[0]
+				buf = buf[1:]
+				if c == '"' {
+					break
+				}
+				if c == '\\' && len(buf) > 0 && (buf[0] == '"' || buf[0] == '\\' || buf[0] == ']') {
+					c = buf[0]
+					buf = buf[1:]
+				}
+				value = append(value, c)
+			}
+			param.value = string(value)
+			element.params = append(element.params, param)
+
+			if len(buf) == 0 {
+				return nil, errTruncated
+			}
+		}
+
+		if buf[0] != ']' {
+			return nil, fmt.Errorf("invalid syslog structured data in %q", truncate(buf))
+		}
+		buf = buf[1:]
+		msg.sd = append(msg.sd, element)
+	}
+	return buf, nil
+}
+
+// parseRFC3164 reads the part of a BSD syslog message following the
+// priority:
+//
+//	Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+//
+// The timestamp is in the local time zone and in the year of now, or the
+// previous one for dates after now. Messages without a valid timestamp are
+// taken as MSG only.
+func (msg *message) parseRFC3164(buf []byte, now time.Time) error {
+	if len(buf) < len(rfc3164Stamp)+1 || buf[len(rfc3164Stamp)] != ' ' {
+		msg.msg = string(buf)
+		return nil
+	}
+	t, err := time.ParseInLocation(rfc3164Stamp, string(buf[:len(rfc3164Stamp)]), time.Local)
+	if err != nil {
+		msg.msg = string(buf)
+		return nil
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	msg.timestamp = t
+	buf = buf[len(rfc3164Stamp)+1:]
+
+	sp := bytes.IndexByte(buf, ' ')
+	if sp < 0 {
+		msg.hostname = string(buf)
+		return nil
+	}
+	msg.hostname = string(buf[:sp])
+	buf = buf[sp+1:]
+
+	// the tag is followed by the pid in brackets or by a colon, otherwise
+	// it is part of the message.
+	end := bytes.IndexAny(buf, "[: ")
+	if end > 0 && buf[end] == '[' {
+		pidEnd := bytes.Index(buf[end:], []byte("]:"))
+		if pidEnd > 0 {
+			msg.appname = string(buf[:end])
+			msg.procid = string(buf[end+1 : end+pidEnd])
+			buf = buf[end+pidEnd+2:]
+		}
+	} else if end > 0 && buf[end] == ':' {
+		msg.appname = string(buf[:end])
+		buf = buf[end+1:]
+	}
+	msg.msg = string(bytes.TrimPrefix(buf, []byte(" ")))
+	return nil
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// truncate shortens buf for error messages.
+func truncate(buf []byte) []byte {
+	if len(buf) > 32 {
+		return buf[:32]
+	}
+	return buf
+}
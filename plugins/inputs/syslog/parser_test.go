@@ -0,0 +1,144 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2017, time.December, 10, 12, 0, 0, 0, time.Local)
+
+func newTestParser() *Parser {
+	return &Parser{
+		Separator: "_",
+		now:       func() time.Time { return now },
+	}
+}
+
+func TestParseRFC5424(t *testing.T) {
+	p := newTestParser()
+	metrics, err := p.Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 ` +
+		`[exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][origin] ` +
+		"\xEF\xBB\xBFAn application event log entry...\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.Equal(t, "syslog", m.Name())
+	assert.Equal(t, map[string]string{
+		"severity": "notice",
+		"facility": "local4",
+		"hostname": "mymachine.example.com",
+		"appname":  "evntslog",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"severity_code":                 int64(5),
+		"facility_code":                 int64(20),
+		"version":                       int64(1),
+		"msgid":                         "ID47",
+		"message":                       "An application event log entry...",
+		"exampleSDID@32473_iut":         "3",
+		"exampleSDID@32473_eventSource": "Application",
+		"exampleSDID@32473_eventID":     "1011",
+		"origin":                        true,
+	}, m.Fields())
+	assert.Equal(t, time.Date(2003, time.October, 11, 22, 14, 15, 3000000, time.UTC).UnixNano(), m.UnixNano())
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	p := newTestParser()
+	m, err := p.ParseLine(`<34>1 - - - 1234 - -`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"severity": "crit",
+		"facility": "auth",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"severity_code": int64(2),
+		"facility_code": int64(4),
+		"version":       int64(1),
+		"procid":        "1234",
+	}, m.Fields())
+	assert.Equal(t, now.UnixNano(), m.UnixNano())
+}
+
+func TestParseRFC5424EscapedParams(t *testing.T) {
+	p := newTestParser()
+	p.Separator = "."
+	m, err := p.ParseLine(`<14>1 2017-12-10T11:00:00+01:00 host app 42 - [meta path="C:\\dir \"x\" \]"] done`)
+	require.NoError(t, err)
+
+	assert.Equal(t, `C:\dir "x" ]`, m.Fields()["meta.path"])
+	assert.Equal(t, "done", m.Fields()["message"])
+	assert.Equal(t, "42", m.Fields()["procid"])
+	assert.Equal(t, time.Date(2017, time.December, 10, 10, 0, 0, 0, time.UTC).UnixNano(), m.UnixNano())
+}
+
+func TestParseRFC3164(t *testing.T) {
+	p := newTestParser()
+	m, err := p.ParseLine(`<38>Dec  9 22:14:15 mymachine sshd[1234]: Accepted publickey for root`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"severity": "info",
+		"facility": "auth",
+		"hostname": "mymachine",
+		"appname":  "sshd",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"severity_code": int64(6),
+		"facility_code": int64(4),
+		"procid":        "1234",
+		"message":       "Accepted publickey for root",
+	}, m.Fields())
+	assert.Equal(t, time.Date(2017, time.December, 9, 22, 14, 15, 0, time.Local).UnixNano(), m.UnixNano())
+
+	m, err = p.ParseLine(`<13>Dec 31 23:59:59 mymachine su: 'su root' failed on /dev/pts/8`)
+	require.NoError(t, err)
+	assert.Equal(t, "su", m.Tags()["appname"])
+	assert.Equal(t, "'su root' failed on /dev/pts/8", m.Fields()["message"])
+	// a date after now is in the previous year
+	assert.Equal(t, time.Date(2016, time.December, 31, 23, 59, 59, 0, time.Local).UnixNano(), m.UnixNano())
+}
+
+func TestParseRFC3164WithoutTimestamp(t *testing.T) {
+	p := newTestParser()
+	m, err := p.ParseLine(`<13>hello world`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello world", m.Fields()["message"])
+	assert.Equal(t, now.UnixNano(), m.UnixNano())
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	p := newTestParser()
+	p.SetDefaultTags(map[string]string{"source": "router", "hostname": "default"})
+	m, err := p.ParseLine(`<34>1 - host - - - -`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "router", m.Tags()["source"])
+	assert.Equal(t, "host", m.Tags()["hostname"])
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := newTestParser()
+	for _, line := range []string{
+		`no priority`,
+		`<192>1 - - - - - -`,
+		`<34>1 - - -`,
+		`<34>1 yesterday host app - - -`,
+		`<34>1 - - - - - [id param="unterminated]`,
+		`<34>1 - - - - - [id param=value]`,
+		`<34>1 - - - - - nosd`,
+	} {
+		_, err := p.ParseLine(line)
+		assert.Error(t, err, line)
+	}
+
+	metrics, err := p.Parse([]byte("\n"))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
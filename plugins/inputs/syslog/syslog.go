@@ -0,0 +1,156 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/socket_listener"
+)
+
+// maxFrameLengthDigits bounds the length prefix of octet counted frames, the
+// scanner can not hold messages of more than 64KB anyway.
+const maxFrameLengthDigits = 5
+
+// Syslog is a syslog receiver, it uses the transport of the socket listener
+// with a parser for syslog messages.
+type Syslog struct {
+	ServiceAddress  string
+	MaxConnections  int
+	ReadBufferSize  int
+	ReadTimeout     *internal.Duration
+	KeepAlivePeriod *internal.Duration
+
+	TlsAllowedCacerts []string
+	TlsCert           string
+	TlsKey            string
+
+	SdparamSeparator string
+
+	listener *socket_listener.SocketListener
+}
+
+var sampleConfig = `
+  ## URL to listen on, stream sockets accept messages with octet counting
+  ## and newline framing (RFC6587), datagram sockets a message per packet.
+  # service_address = "tcp://:6514"
+  # service_address = "tcp4://:6514"
+  # service_address = "udp://:6514"
+  # service_address = "unix:///tmp/telegraf-syslog.sock"
+
+  ## Maximum number of concurrent connections.
+  ## Only applies to stream sockets (e.g. TCP).
+  ## 0 (default) is unlimited.
+  # max_connections = 1024
+
+  ## Read timeout.
+  ## Only applies to stream sockets (e.g. TCP).
+  ## 0 (default) is unlimited.
+  # read_timeout = "30s"
+
+  ## Maximum socket buffer size in bytes.
+  ## Defaults to the OS default.
+  # read_buffer_size = 65535
+
+  ## Period between keep alive probes.
+  ## Only applies to TCP sockets.
+  ## 0 disables keep alive probes.
+  ## Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## Set the service certificate and key to accept TLS connections (RFC5425).
+  ## Only applies to stream sockets (e.g. TCP).
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Separator between the structured data element id and the parameter
+  ## names in the field keys.
+  # sdparam_separator = "_"
+`
+
+func (s *Syslog) Description() string {
+	return "Accepts syslog messages in the RFC5424 or RFC3164 format."
+}
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *Syslog) Start(acc telegraf.Accumulator) error {
+	s.listener = &socket_listener.SocketListener{
+		ServiceAddress:    s.ServiceAddress,
+		MaxConnections:    s.MaxConnections,
+		ReadBufferSize:    s.ReadBufferSize,
+		ReadTimeout:       s.ReadTimeout,
+		KeepAlivePeriod:   s.KeepAlivePeriod,
+		TlsAllowedCacerts: s.TlsAllowedCacerts,
+		TlsCert:           s.TlsCert,
+		TlsKey:            s.TlsKey,
+		SplitFunc:         splitFrame,
+		Parser:            &Parser{Separator: s.SdparamSeparator},
+	}
+	return s.listener.Start(acc)
+}
+
+func (s *Syslog) Stop() {
+	if s.listener != nil {
+		s.listener.Stop()
+	}
+}
+
+// splitFrame splits the data of stream sockets into messages, as described
+// in RFC6587: each message is either prefixed by its length and a space
+// (octet counting), or terminated by a newline (non-transparent framing).
+// The framing of each message is told by its first character, as messages
+// start with '<'.
+func splitFrame(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 || data[0] < '0' || data[0] > '9' {
+		return bufio.ScanLines(data, atEOF)
+	}
+
+	sp := bytes.IndexByte(data, ' ')
+	if sp < 0 {
+		if len(data) > maxFrameLengthDigits {
+			return 0, nil, fmt.Errorf("invalid syslog frame length %q", data[:maxFrameLengthDigits])
+		}
+		if atEOF {
+			return 0, nil, errors.New("truncated syslog frame")
+		}
+		return 0, nil, nil
+	}
+
+	length, err := strconv.Atoi(string(data[:sp]))
+	if err != nil || sp > maxFrameLengthDigits {
+		return 0, nil, fmt.Errorf("invalid syslog frame length %q", data[:sp])
+	}
+	end := sp + 1 + length
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, errors.New("truncated syslog frame")
+		}
+		return 0, nil, nil
+	}
+	return end, data[sp+1 : end], nil
+}
+
+func init() {
+	inputs.Add("syslog", func() telegraf.Input {
+		return &Syslog{
+			ServiceAddress:   "tcp://:6514",
+			SdparamSeparator: "_",
+		}
+	})
+}
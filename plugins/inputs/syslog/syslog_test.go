@@ -0,0 +1,86 @@
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSplitFrame(t *testing.T) {
+	data := "26 <34>1 - host app - - - one" +
+		"<34>1 - host app - - - two\n" +
+		"29 <34>1 - host app - - - th\nree\n"
+
+	scnr := bufio.NewScanner(strings.NewReader(data))
+	scnr.Split(splitFrame)
+	var frames []string
+	for scnr.Scan() {
+		frames = append(frames, scnr.Text())
+	}
+	require.NoError(t, scnr.Err())
+	assert.Equal(t, []string{
+		"<34>1 - host app - - - one",
+		"<34>1 - host app - - - two",
+		"<34>1 - host app - - - th\nree",
+		"",
+	}, frames)
+
+	scnr = bufio.NewScanner(strings.NewReader("100 <34>1 - host app - - - short"))
+	scnr.Split(splitFrame)
+	assert.False(t, scnr.Scan())
+	assert.Error(t, scnr.Err())
+}
+
+func TestSyslog_tcp(t *testing.T) {
+	s := &Syslog{
+		ServiceAddress:   "tcp://127.0.0.1:0",
+		SdparamSeparator: "_",
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	client, err := net.Dial("tcp", s.listener.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	client.Write([]byte("33 <14>1 - host app - - [a b=\"c\"] x\n" +
+		"<14>1 - host app - - - y\n"))
+
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, "x", acc.Metrics[0].Fields["message"])
+	assert.Equal(t, "c", acc.Metrics[0].Fields["a_b"])
+	assert.Equal(t, "y", acc.Metrics[1].Fields["message"])
+	assert.Equal(t, "host", acc.Metrics[1].Tags["hostname"])
+}
+
+func TestSyslog_udp(t *testing.T) {
+	s := &Syslog{
+		ServiceAddress:   "udp://127.0.0.1:0",
+		SdparamSeparator: "_",
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	client, err := net.Dial("udp", s.listener.Closer.(net.PacketConn).LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	client.Write([]byte("<38>Dec  9 22:14:15 mymachine sshd[1234]: line one\nline two"))
+
+	acc.Wait(1)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, "syslog", acc.Metrics[0].Measurement)
+	assert.Equal(t, "sshd", acc.Metrics[0].Tags["appname"])
+	assert.Equal(t, "line one\nline two", acc.Metrics[0].Fields["message"])
+}
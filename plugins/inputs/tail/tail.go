@@ -3,7 +3,9 @@
 package tail
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 
@@ -25,10 +27,11 @@ type Tail struct {
 	Pipe          bool
 	WatchMethod   string
 
-	tailers []*tail.Tail
-	parser  parsers.Parser
-	wg      sync.WaitGroup
-	acc     telegraf.Accumulator
+	tailers    []*tail.Tail
+	parser     parsers.Parser
+	parserFunc parsers.ParserFunc
+	wg         sync.WaitGroup
+	acc        telegraf.Accumulator
 
 	sync.Mutex
 }
@@ -103,6 +106,11 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			t.acc.AddError(fmt.Errorf("E! Error Glob %s failed to compile, %s", filepath, err))
 		}
 		for file, _ := range g.Match() {
+			parser, err := t.newParser(file, seek != nil)
+			if err != nil {
+				acc.AddError(err)
+				continue
+			}
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
@@ -119,7 +127,7 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			}
 			// create a goroutine for each "tailer"
 			t.wg.Add(1)
-			go t.receiver(tailer)
+			go t.receiver(parser, tailer)
 			t.tailers = append(t.tailers, tailer)
 		}
 	}
@@ -127,9 +135,47 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// headerParser is implemented by the parsers which read a header from the
+// first lines of a file, such as the csv parser.
+type headerParser interface {
+	// HeaderPending returns true until the header has been parsed.
+	HeaderPending() bool
+}
+
+// newParser returns the parser of a file, each file has its own parser as
+// parsers may keep state between lines. When the file is tailed from its
+// end, its header is read first.
+func (t *Tail) newParser(file string, fromEnd bool) (parsers.Parser, error) {
+	if t.parserFunc == nil {
+		return t.parser, nil
+	}
+	parser, err := t.parserFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	hp, ok := parser.(headerParser)
+	if !ok || !fromEnd || !hp.HeaderPending() {
+		return parser, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for hp.HeaderPending() && scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if _, err := parser.ParseLine(text); err != nil {
+			return nil, fmt.Errorf("E! Malformed header in %s: %s", file, err)
+		}
+	}
+	return parser, scanner.Err()
+}
+
 // this is launched as a goroutine to continuously watch a tailed logfile
 // for changes, parse any incoming msgs, and add to the accumulator.
-func (t *Tail) receiver(tailer *tail.Tail) {
+func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 	defer t.wg.Done()
 
 	var m telegraf.Metric
@@ -144,9 +190,12 @@ func (t *Tail) receiver(tailer *tail.Tail) {
 		// Fix up files with Windows line endings.
 		text := strings.TrimRight(line.Text, "\r")
 
-		m, err = t.parser.ParseLine(text)
+		m, err = parser.ParseLine(text)
 		if err == nil {
-			t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+			// parsers return no metric for lines such as headers
+			if m != nil {
+				t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+			}
 		} else {
 			t.acc.AddError(fmt.Errorf("E! Malformed log line in %s: [%s], Error: %s\n",
 				tailer.Filename, line.Text, err))
@@ -176,6 +225,10 @@ func (t *Tail) SetParser(parser parsers.Parser) {
 	t.parser = parser
 }
 
+func (t *Tail) SetParserFunc(fn parsers.ParserFunc) {
+	t.parserFunc = fn
+}
+
 func init() {
 	inputs.Add("tail", func() telegraf.Input {
 		return NewTail()
@@ -133,3 +133,51 @@ func TestTailDosLineendings(t *testing.T) {
 			"usage_idle": float64(200),
 		})
 }
+
+func TestTailCSVHeaderPerFile(t *testing.T) {
+	var files []*os.File
+	for _, header := range []string{"a,b", "c,d"} {
+		tmpfile, err := ioutil.TempFile("", "")
+		require.NoError(t, err)
+		defer os.Remove(tmpfile.Name())
+		defer tmpfile.Close()
+		_, err = tmpfile.WriteString(header + "\n1,2\n")
+		require.NoError(t, err)
+		files = append(files, tmpfile)
+	}
+
+	// the header of each file is read, although tailing starts at the end
+	tt := NewTail()
+	tt.Files = []string{files[0].Name(), files[1].Name()}
+	tt.SetParserFunc(func() (parsers.Parser, error) {
+		return parsers.NewParser(&parsers.Config{
+			DataFormat:        "csv",
+			MetricName:        "csv",
+			CSVHeaderRowCount: 1,
+		})
+	})
+	defer tt.Stop()
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+	for _, tailer := range tt.tailers {
+		for n, err := tailer.Tell(); err == nil && n == 0; n, err = tailer.Tell() {
+			// wait for tailer to jump to end
+			runtime.Gosched()
+		}
+	}
+
+	for _, f := range files {
+		_, err := f.WriteString("3,4\n")
+		require.NoError(t, err)
+	}
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	var fields []map[string]interface{}
+	for _, m := range acc.Metrics {
+		fields = append(fields, m.Fields)
+	}
+	assert.Contains(t, fields, map[string]interface{}{"a": int64(3), "b": int64(4)})
+	assert.Contains(t, fields, map[string]interface{}{"c": int64(3), "d": int64(4)})
+}
@@ -218,8 +218,7 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 		return fmt.Errorf("connection is not open")
 	}
 
-	outbuf := make(map[string][]byte)
-
+	batches := make(map[string][]telegraf.Metric)
 	for _, metric := range metrics {
 		var key string
 		if q.RoutingTag != "" {
@@ -227,19 +226,18 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 				key = h
 			}
 		}
+		batches[key] = append(batches[key], metric)
+	}
 
-		buf, err := q.serializer.Serialize(metric)
+	for key, batch := range batches {
+		buf, err := serializers.SerializeBatch(q.serializer, batch)
 		if err != nil {
 			return err
 		}
 
-		outbuf[key] = append(outbuf[key], buf...)
-	}
-
-	for key, buf := range outbuf {
 		// Note that since the channel is not in confirm mode, the absence of
 		// an error does not indicate successful delivery.
-		err := c.channel.Publish(
+		err = c.channel.Publish(
 			q.Exchange, // exchange
 			key,        // routing key
 			false,      // mandatory
@@ -90,6 +90,18 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if serializers.Batching(f.serializer) {
+		b, err := serializers.SerializeBatch(f.serializer, metrics)
+		if err != nil {
+			return fmt.Errorf("failed to serialize message: %s", err)
+		}
+		_, err = f.writer.Write(b)
+		if err != nil {
+			return fmt.Errorf("failed to write message: %s", err)
+		}
+		return nil
+	}
+
 	for _, metric := range metrics {
 		b, err := f.serializer.Serialize(metric)
 		if err != nil {
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -170,6 +171,32 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileBatchSerializer(t *testing.T) {
+	s, _ := serializers.NewSerializer(&serializers.Config{
+		DataFormat:     "json",
+		TimestampUnits: time.Second,
+		JSONBatch:      true,
+	})
+	fh := tmpFile()
+	f := File{
+		Files:      []string{fh},
+		serializer: s,
+	}
+
+	err := f.Connect()
+	assert.NoError(t, err)
+
+	metrics := append(testutil.MockMetrics(), testutil.MockMetrics()...)
+	err = f.Write(metrics)
+	assert.NoError(t, err)
+
+	obj := `{"fields":{"value":1},"name":"test1","tags":{"tag1":"value1"},"timestamp":1257894000}`
+	validateFile(fh, "["+obj+","+obj+"]\n", t)
+
+	err = f.Close()
+	assert.NoError(t, err)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
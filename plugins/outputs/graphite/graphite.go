@@ -3,6 +3,7 @@ package graphite
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/rand"
@@ -20,8 +21,12 @@ type Graphite struct {
 	Servers  []string
 	Prefix   string
 	Template string
-	Timeout  int
-	conns    []net.Conn
+	// GraphiteTagSupport writes tagged series instead of using the Template.
+	GraphiteTagSupport bool `toml:"graphite_tag_support"`
+	// DataFormat is either "graphite" or "carbon2".
+	DataFormat string `toml:"data_format"`
+	Timeout    int
+	conns      []net.Conn
 
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
@@ -46,6 +51,11 @@ var sampleConfig = `
   ## Graphite output template
   ## see https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   template = "host.tags.measurement.field"
+  ## Write Graphite 1.1 tagged series, ie "cpu.usage_idle;host=server01",
+  ## instead of putting the tags in the path with the template.
+  # graphite_tag_support = false
+  ## Data format to write, either "graphite" or "carbon2".
+  # data_format = "graphite"
   ## timeout in seconds for the write connection to graphite
   timeout = 2
 
@@ -65,6 +75,14 @@ func (g *Graphite) Connect() error {
 	if len(g.Servers) == 0 {
 		g.Servers = append(g.Servers, "localhost:2003")
 	}
+	switch g.DataFormat {
+	case "":
+		g.DataFormat = "graphite"
+	case "graphite", "carbon2":
+	default:
+		return fmt.Errorf("invalid data_format %q, must be graphite or carbon2",
+			g.DataFormat)
+	}
 
 	// Set tls config
 	var err error
@@ -142,7 +160,12 @@ func checkEOF(conn net.Conn) {
 func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	// Prepare data
 	var batch []byte
-	s, err := serializers.NewGraphiteSerializer(g.Prefix, g.Template)
+	s, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat:         g.DataFormat,
+		Prefix:             g.Prefix,
+		Template:           g.Template,
+		GraphiteTagSupport: g.GraphiteTagSupport,
+	})
 	if err != nil {
 		return err
 	}
@@ -247,6 +247,10 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if serializers.Batching(k.serializer) {
+		return k.writeBatches(metrics)
+	}
+
 	for _, metric := range metrics {
 		buf, err := k.serializer.Serialize(metric)
 		if err != nil {
@@ -272,6 +276,47 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// writeBatches sends a message per topic and routing key, holding all their
+// metrics serialized at once.
+func (k *Kafka) writeBatches(metrics []telegraf.Metric) error {
+	type batchKey struct {
+		topic string
+		key   string
+		keyed bool
+	}
+	var keys []batchKey
+	batches := make(map[batchKey][]telegraf.Metric)
+	for _, metric := range metrics {
+		bk := batchKey{topic: k.GetTopicName(metric)}
+		bk.key, bk.keyed = metric.Tags()[k.RoutingTag]
+		if _, ok := batches[bk]; !ok {
+			keys = append(keys, bk)
+		}
+		batches[bk] = append(batches[bk], metric)
+	}
+
+	for _, bk := range keys {
+		buf, err := serializers.SerializeBatch(k.serializer, batches[bk])
+		if err != nil {
+			return err
+		}
+
+		m := &sarama.ProducerMessage{
+			Topic: bk.topic,
+			Value: sarama.ByteEncoder(buf),
+		}
+		if bk.keyed {
+			m.Key = sarama.StringEncoder(bk.key)
+		}
+
+		_, _, err = k.producer.SendMessage(m)
+		if err != nil {
+			return fmt.Errorf("FAILED to send kafka message: %s\n", err)
+		}
+	}
+	return nil
+}
+
 func init() {
 	outputs.Add("kafka", func() telegraf.Output {
 		return &Kafka{
@@ -120,17 +120,12 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 		hostname = ""
 	}
 
-	for _, metric := range metrics {
-		var t []string
-		if m.TopicPrefix != "" {
-			t = append(t, m.TopicPrefix)
-		}
-		if hostname != "" {
-			t = append(t, hostname)
-		}
+	if serializers.Batching(m.serializer) {
+		return m.publishBatches(hostname, metrics)
+	}
 
-		t = append(t, metric.Name())
-		topic := strings.Join(t, "/")
+	for _, metric := range metrics {
+		topic := m.topic(hostname, metric)
 
 		buf, err := m.serializer.Serialize(metric)
 		if err != nil {
@@ -147,6 +142,46 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// publishBatches publishes a message per topic, holding all its metrics
+// serialized at once.
+func (m *MQTT) publishBatches(hostname string, metrics []telegraf.Metric) error {
+	var topics []string
+	batches := make(map[string][]telegraf.Metric)
+	for _, metric := range metrics {
+		topic := m.topic(hostname, metric)
+		if _, ok := batches[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		batches[topic] = append(batches[topic], metric)
+	}
+
+	for _, topic := range topics {
+		buf, err := serializers.SerializeBatch(m.serializer, batches[topic])
+		if err != nil {
+			return fmt.Errorf("MQTT Could not serialize metrics: %s", err)
+		}
+
+		err = m.publish(topic, buf)
+		if err != nil {
+			return fmt.Errorf("Could not write to MQTT server, %s", err)
+		}
+	}
+	return nil
+}
+
+func (m *MQTT) topic(hostname string, metric telegraf.Metric) string {
+	var t []string
+	if m.TopicPrefix != "" {
+		t = append(t, m.TopicPrefix)
+	}
+	if hostname != "" {
+		t = append(t, hostname)
+	}
+
+	t = append(t, metric.Name())
+	return strings.Join(t, "/")
+}
+
 func (m *MQTT) publish(topic string, body []byte) error {
 	token := m.client.Publish(topic, byte(m.QoS), false, body)
 	token.Wait()
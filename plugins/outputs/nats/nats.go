@@ -114,6 +114,18 @@ func (n *NATS) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if serializers.Batching(n.serializer) {
+		buf, err := serializers.SerializeBatch(n.serializer, metrics)
+		if err != nil {
+			return err
+		}
+		err = n.conn.Publish(n.Subject, buf)
+		if err != nil {
+			return fmt.Errorf("FAILED to send NATS message: %s", err)
+		}
+		return nil
+	}
+
 	for _, metric := range metrics {
 		buf, err := n.serializer.Serialize(metric)
 		if err != nil {
@@ -104,19 +104,24 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 		}
 	}
 
+	// batches are written at once on stream sockets only, as they could
+	// exceed the maximum size of a datagram
+	if serializers.Batching(sw.Serializer) && !sw.isPacket() {
+		bs, err := serializers.SerializeBatch(sw.Serializer, metrics)
+		if err != nil {
+			return err
+		}
+		return sw.write(bs)
+	}
+
 	for _, m := range metrics {
 		bs, err := sw.Serialize(m)
 		if err != nil {
 			//TODO log & keep going with remaining metrics
 			return err
 		}
-		if _, err := sw.Conn.Write(bs); err != nil {
+		if err := sw.write(bs); err != nil {
 			//TODO log & keep going with remaining strings
-			if err, ok := err.(net.Error); !ok || !err.Temporary() {
-				// permanent error. close the connection
-				sw.Close()
-				sw.Conn = nil
-			}
 			return err
 		}
 	}
@@ -124,6 +129,29 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// isPacket returns whether the address is the one of a datagram socket.
+func (sw *SocketWriter) isPacket() bool {
+	switch strings.SplitN(sw.Address, "://", 2)[0] {
+	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
+		return true
+	default:
+		return false
+	}
+}
+
+// write writes bs to the connection, which is closed on permanent errors.
+func (sw *SocketWriter) write(bs []byte) error {
+	if _, err := sw.Conn.Write(bs); err != nil {
+		if err, ok := err.(net.Error); !ok || !err.Temporary() {
+			// permanent error. close the connection
+			sw.Close()
+			sw.Conn = nil
+		}
+		return err
+	}
+	return nil
+}
+
 // Close closes the connection. Noop if already closed.
 func (sw *SocketWriter) Close() error {
 	if sw.Conn == nil {
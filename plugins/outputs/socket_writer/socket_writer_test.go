@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,6 +44,25 @@ func TestSocketWriter_udp(t *testing.T) {
 	testSocketWriter_packet(t, sw, listener)
 }
 
+func TestSocketWriter_udpBatching(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "udp://" + listener.LocalAddr().String()
+	sw.Serializer, err = serializers.NewSerializer(&serializers.Config{
+		DataFormat: "json",
+		JSONBatch:  true,
+	})
+	require.NoError(t, err)
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	// the metrics are still written a datagram each
+	testSocketWriter_packet(t, sw, listener)
+}
+
 func TestSocketWriter_unix(t *testing.T) {
 	os.Remove("/tmp/telegraf_test.sock")
 	defer os.Remove("/tmp/telegraf_test.sock")
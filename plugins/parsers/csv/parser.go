@@ -0,0 +1,316 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+type CSVParser struct {
+	MetricName string
+	// HeaderRowCount is the number of rows holding the column names, the
+	// names of a column are joined when there are several.
+	HeaderRowCount int
+	// SkipRows is the number of rows skipped before the header.
+	SkipRows int
+	// SkipColumns is the number of columns skipped at the start of each row.
+	SkipColumns int
+	// ColumnNames override the names of the header.
+	ColumnNames []string
+	// ColumnTypes are the types of the columns by position, one of "int",
+	// "float", "bool" or "string". Types are inferred for the columns
+	// without one.
+	ColumnTypes []string
+	// Delimiter separates the columns, it defaults to ",".
+	Delimiter string
+	// Comment starts the lines which are ignored.
+	Comment           string
+	TrimSpace         bool
+	TagColumns        []string
+	MeasurementColumn string
+	// TimestampColumn is the column of the metric timestamp, which is parsed
+	// according to TimestampFormat. Metrics are stamped with the current
+	// time when empty.
+	TimestampColumn string
+	// TimestampFormat is either "unix", "unix_ms", "unix_us", "unix_ns", or a
+	// Go time layout.
+	TimestampFormat string
+	DefaultTags     map[string]string
+
+	delimiter rune
+	comment   rune
+	tags      map[string]bool
+
+	// rows are the rows which ParseLine has yet to skip or to read the
+	// header from. The header is read from the stream of rows of a single
+	// source, a parser must be created for each source.
+	rows    int
+	header  [][]string
+	columns []string
+}
+
+// Init validates the configuration of the parser, it must be called before
+// parsing.
+func (p *CSVParser) Init() error {
+	if p.HeaderRowCount == 0 && len(p.ColumnNames) == 0 {
+		return fmt.Errorf("csv_header_row_count or csv_column_names must be set")
+	}
+	if len(p.ColumnNames) > 0 && len(p.ColumnTypes) > 0 &&
+		len(p.ColumnTypes) != len(p.ColumnNames) {
+		return fmt.Errorf("csv_column_types must have as many entries as csv_column_names")
+	}
+	for _, typ := range p.ColumnTypes {
+		switch typ {
+		case "int", "float", "bool", "string":
+		default:
+			return fmt.Errorf("invalid csv column type %q", typ)
+		}
+	}
+
+	p.delimiter = ','
+	if p.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(p.Delimiter)
+		if size != len(p.Delimiter) {
+			return fmt.Errorf("csv_delimiter must be a single character: %q", p.Delimiter)
+		}
+		p.delimiter = r
+	}
+	p.comment = 0
+	if p.Comment != "" {
+		r, size := utf8.DecodeRuneInString(p.Comment)
+		if size != len(p.Comment) {
+			return fmt.Errorf("csv_comment must be a single character: %q", p.Comment)
+		}
+		p.comment = r
+	}
+	if p.TimestampColumn != "" && p.TimestampFormat == "" {
+		return fmt.Errorf("csv_timestamp_format must be set with csv_timestamp_column")
+	}
+
+	p.tags = make(map[string]bool, len(p.TagColumns))
+	for _, name := range p.TagColumns {
+		p.tags[name] = true
+	}
+	p.rows = p.SkipRows + p.HeaderRowCount
+	p.header = nil
+	p.columns = nil
+	return nil
+}
+
+func (p *CSVParser) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = p.delimiter
+	reader.Comment = p.comment
+	reader.TrimLeadingSpace = p.TrimSpace
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// Parse parses a whole CSV document, starting with the skipped rows and the
+// header.
+func (p *CSVParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	reader := p.newReader(bytes.NewReader(buf))
+
+	for i := 0; i < p.SkipRows; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, p.readError(err)
+		}
+	}
+	header := make([][]string, 0, p.HeaderRowCount)
+	for i := 0; i < p.HeaderRowCount; i++ {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, p.readError(err)
+		}
+		header = append(header, record)
+	}
+	columns := p.columnNames(header)
+
+	metrics := make([]telegraf.Metric, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, err := p.parseRecord(columns, record)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+func (p *CSVParser) readError(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("CSV data ends before its header")
+	}
+	return err
+}
+
+// ParseLine parses a single row. As a stream of rows is passed to ParseLine,
+// the rows to skip and the header are taken from its first lines. No metric
+// is returned for them, nor for empty lines and comments.
+//
+// ParseLine must not be called concurrently, nor with the rows of several
+// sources.
+func (p *CSVParser) ParseLine(line string) (telegraf.Metric, error) {
+	reader := p.newReader(strings.NewReader(line))
+	record, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.rows > 0 {
+		p.rows--
+		if p.rows < p.HeaderRowCount {
+			p.header = append(p.header, record)
+		}
+		if p.rows == 0 {
+			p.columns = p.columnNames(p.header)
+		}
+		return nil, nil
+	}
+	columns := p.columns
+	if columns == nil {
+		columns = p.columnNames(nil)
+	}
+	return p.parseRecord(columns, record)
+}
+
+// HeaderPending returns true until ParseLine has been given the rows to skip
+// and the header.
+func (p *CSVParser) HeaderPending() bool {
+	return p.rows > 0
+}
+
+func (p *CSVParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// columnNames returns the names of the columns, after the skipped ones.
+func (p *CSVParser) columnNames(header [][]string) []string {
+	if len(p.ColumnNames) > 0 {
+		return p.ColumnNames
+	}
+
+	var columns []string
+	for _, row := range header {
+		for i, name := range row {
+			if i < p.SkipColumns {
+				continue
+			}
+			i -= p.SkipColumns
+			if p.TrimSpace {
+				name = strings.TrimSpace(name)
+			}
+			if i < len(columns) {
+				columns[i] += name
+			} else {
+				columns = append(columns, name)
+			}
+		}
+	}
+	for i, name := range columns {
+		if name == "" {
+			columns[i] = "column" + strconv.Itoa(i+1)
+		}
+	}
+	return columns
+}
+
+func (p *CSVParser) parseRecord(columns []string, record []string) (telegraf.Metric, error) {
+	if len(record) < p.SkipColumns {
+		record = nil
+	} else {
+		record = record[p.SkipColumns:]
+	}
+	if len(record) > len(columns) {
+		return nil, fmt.Errorf("CSV record has %d columns, expected %d",
+			len(record), len(columns))
+	}
+
+	name := p.MetricName
+	tags := make(map[string]string, len(p.DefaultTags)+len(p.tags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{}, len(record))
+	t := time.Now().UTC()
+
+	for i, value := range record {
+		column := columns[i]
+		if p.TrimSpace {
+			value = strings.TrimSpace(value)
+		}
+
+		switch {
+		case column == p.MeasurementColumn:
+			name = value
+			continue
+		case column == p.TimestampColumn:
+			var err error
+			t, err = internal.ParseTimestamp(value, p.TimestampFormat)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case value == "":
+			// missing values are left out
+			continue
+		case p.tags[column]:
+			tags[column] = value
+			continue
+		}
+
+		if i < len(p.ColumnTypes) {
+			v, err := convert(value, p.ColumnTypes[i])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %s", column, err)
+			}
+			fields[column] = v
+		} else {
+			fields[column] = internal.InferValue(value)
+		}
+	}
+
+	if p.TimestampColumn != "" && len(record) <= indexOf(columns, p.TimestampColumn) {
+		return nil, fmt.Errorf("CSV record has no timestamp column %s", p.TimestampColumn)
+	}
+	return metric.New(name, tags, fields, t)
+}
+
+func indexOf(columns []string, name string) int {
+	for i, column := range columns {
+		if column == name {
+			return i
+		}
+	}
+	return len(columns)
+}
+
+func convert(value string, typ string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,229 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newParser(t *testing.T, p *CSVParser) *CSVParser {
+	p.MetricName = "csv"
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestHeaderRow(t *testing.T) {
+	p := newParser(t, &CSVParser{
+		HeaderRowCount: 1,
+		TagColumns:     []string{"host"},
+	})
+	metrics, err := p.Parse([]byte(`host,cpu,load,up,status
+server01,12,0.5,true,ok
+server02,5,1.25,false,
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "csv", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "server01"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"cpu":    int64(12),
+		"load":   0.5,
+		"up":     true,
+		"status": "ok",
+	}, metrics[0].Fields())
+
+	// empty values are left out
+	assert.Equal(t, map[string]interface{}{
+		"cpu":  int64(5),
+		"load": 1.25,
+		"up":   false,
+	}, metrics[1].Fields())
+}
+
+func TestColumnNamesAndTypes(t *testing.T) {
+	p := newParser(t, &CSVParser{
+		ColumnNames: []string{"name", "code", "value"},
+		ColumnTypes: []string{"string", "string", "float"},
+		Delimiter:   ";",
+		Comment:     "#",
+		TrimSpace:   true,
+	})
+	metrics, err := p.Parse([]byte(`# exported by the batch job
+ disk ; 007 ; 3
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "disk",
+		"code":  "007",
+		"value": 3.0,
+	}, metrics[0].Fields())
+
+	p = newParser(t, &CSVParser{
+		ColumnNames: []string{"value"},
+		ColumnTypes: []string{"int"},
+	})
+	_, err = p.Parse([]byte("1.5\n"))
+	assert.Error(t, err)
+}
+
+func TestInferDecimal(t *testing.T) {
+	p := newParser(t, &CSVParser{HeaderRowCount: 1})
+	metrics, err := p.Parse([]byte(`a,b,c,d,e,f,g
+1.5e3,nan,inf,0x1p-2,t,TRUE,true
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"a": 1500.0,
+		"b": "nan",
+		"c": "inf",
+		"d": "0x1p-2",
+		"e": "t",
+		"f": "TRUE",
+		"g": true,
+	}, metrics[0].Fields())
+}
+
+func TestHeaderRowAndTypes(t *testing.T) {
+	// types apply by position, and are inferred for the columns after them
+	p := newParser(t, &CSVParser{
+		HeaderRowCount: 1,
+		ColumnTypes:    []string{"string", "float"},
+	})
+	metrics, err := p.Parse([]byte(`code,value,count
+007,3,4
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"code":  "007",
+		"value": 3.0,
+		"count": int64(4),
+	}, metrics[0].Fields())
+}
+
+func TestSkipRowsAndColumns(t *testing.T) {
+	p := newParser(t, &CSVParser{
+		SkipRows:       2,
+		HeaderRowCount: 2,
+		SkipColumns:    1,
+	})
+	metrics, err := p.Parse([]byte(`Report generated 2017-11-01
+,
+id,mem_,cpu_,
+id,used,idle,
+1,100,90,2
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	// header rows are joined, and unnamed columns are numbered
+	assert.Equal(t, map[string]interface{}{
+		"mem_used": int64(100),
+		"cpu_idle": int64(90),
+		"column3":  int64(2),
+	}, metrics[0].Fields())
+}
+
+func TestMeasurementAndTimestamp(t *testing.T) {
+	p := newParser(t, &CSVParser{
+		HeaderRowCount:    1,
+		MeasurementColumn: "measurement",
+		TimestampColumn:   "time",
+		TimestampFormat:   "2006-01-02 15:04:05",
+	})
+	metrics, err := p.Parse([]byte(`measurement,time,value
+cpu,2017-07-14 02:40:00,42
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "cpu", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"value": int64(42)}, metrics[0].Fields())
+	assert.True(t, time.Unix(1500000000, 0).Equal(metrics[0].Time()))
+
+	_, err = p.Parse([]byte(`measurement,time,value
+cpu,yesterday,42
+`))
+	assert.Error(t, err)
+}
+
+func TestUnixTimestamp(t *testing.T) {
+	tests := []struct {
+		format   string
+		value    string
+		expected time.Time
+	}{
+		{"unix", "1500000000", time.Unix(1500000000, 0)},
+		{"unix", "1500000000.25", time.Unix(1500000000, 250000000)},
+		{"unix_ms", "1500000000123", time.Unix(1500000000, 123000000)},
+		{"unix_us", "1500000000123456", time.Unix(1500000000, 123456000)},
+		{"unix_ns", "1500000000123456789", time.Unix(1500000000, 123456789)},
+	}
+	for _, tt := range tests {
+		p := newParser(t, &CSVParser{
+			ColumnNames:     []string{"time", "value"},
+			TimestampColumn: "time",
+			TimestampFormat: tt.format,
+		})
+		metrics, err := p.Parse([]byte(tt.value + ",1\n"))
+		require.NoError(t, err, tt.format)
+		require.Len(t, metrics, 1)
+		assert.True(t, tt.expected.Equal(metrics[0].Time()), tt.format)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	p := newParser(t, &CSVParser{
+		SkipRows:       1,
+		HeaderRowCount: 1,
+		TagColumns:     []string{"host"},
+		Comment:        "#",
+	})
+
+	// the skipped row and the header produce no metric
+	assert.True(t, p.HeaderPending())
+	for _, line := range []string{"vendor log v2", "host,value", "", "# comment"} {
+		m, err := p.ParseLine(line)
+		require.NoError(t, err)
+		assert.Nil(t, m)
+	}
+	assert.False(t, p.HeaderPending())
+
+	m, err := p.ParseLine("server01,42")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "server01"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(42)}, m.Fields())
+}
+
+func TestDefaultTags(t *testing.T) {
+	p := newParser(t, &CSVParser{ColumnNames: []string{"host", "value"}, TagColumns: []string{"host"}})
+	p.SetDefaultTags(map[string]string{"dc": "eu", "host": "default"})
+
+	metrics, err := p.Parse([]byte("server01,1\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{"dc": "eu", "host": "server01"}, metrics[0].Tags())
+}
+
+func TestErrors(t *testing.T) {
+	for _, p := range []*CSVParser{
+		{},
+		{ColumnNames: []string{"a"}, ColumnTypes: []string{"int", "int"}},
+		{ColumnNames: []string{"a"}, ColumnTypes: []string{"decimal"}},
+		{ColumnNames: []string{"a"}, Delimiter: "::"},
+		{ColumnNames: []string{"a"}, TimestampColumn: "a"},
+	} {
+		assert.Error(t, p.Init())
+	}
+
+	p := newParser(t, &CSVParser{ColumnNames: []string{"a", "b"}})
+	_, err := p.Parse([]byte("1,2,3\n"))
+	assert.Error(t, err)
+
+	p = newParser(t, &CSVParser{HeaderRowCount: 2})
+	_, err = p.Parse([]byte("a,b\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package dropwizard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+)
+
+// metricTypes maps the sections of a metric registry to the type of their
+// metrics. Meters hold a count along with rates, so they are untyped.
+var metricTypes = []struct {
+	section   string
+	valueType telegraf.ValueType
+}{
+	{"gauges", telegraf.Gauge},
+	{"counters", telegraf.Counter},
+	{"histograms", telegraf.Summary},
+	{"meters", telegraf.Untyped},
+	{"timers", telegraf.Summary},
+}
+
+// DropwizardParser parses the JSON representation of a Dropwizard metric
+// registry, as written by the metrics servlet. The registry is an object
+// with "gauges", "counters", "histograms", "meters" and "timers" objects,
+// which map the metric names to their values. Each metric of the registry
+// becomes a telegraf metric, with the values of its object as fields.
+type DropwizardParser struct {
+	// MetricRegistryPath is the path to the registry in the document, with
+	// the keys of objects and the indices of arrays separated by dots. The
+	// whole document is the registry when empty.
+	MetricRegistryPath string
+	// Templates extract the measurement, tags and field prefix from the
+	// metric names, as in the graphite parser. The measurement is the metric
+	// name when no template is set.
+	Templates []string
+	// Separator joins the parts of the measurement extracted by templates.
+	Separator   string
+	DefaultTags map[string]string
+
+	templateEngine *graphite.GraphiteParser
+}
+
+// Init compiles the templates, it must be called before parsing when they
+// are set.
+func (p *DropwizardParser) Init() error {
+	if len(p.Templates) == 0 {
+		p.templateEngine = nil
+		return nil
+	}
+	engine, err := graphite.NewGraphiteParser(p.Separator, p.Templates, nil)
+	if err != nil {
+		return fmt.Errorf("invalid dropwizard templates: %s", err)
+	}
+	p.templateEngine = engine
+	return nil
+}
+
+func (p *DropwizardParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return make([]telegraf.Metric, 0), nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse out as JSON, %s", err)
+	}
+	registry, err := p.findRegistry(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	metrics := make([]telegraf.Metric, 0)
+	for _, mt := range metricTypes {
+		section, ok := registry[mt.section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		names := make([]string, 0, len(section))
+		for name := range section {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			values, ok := section[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			m, err := p.newMetric(name, values, mt.valueType, now)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// findRegistry returns the object found at the MetricRegistryPath.
+func (p *DropwizardParser) findRegistry(doc interface{}) (map[string]interface{}, error) {
+	if p.MetricRegistryPath != "" {
+		var err error
+		doc, err = internal.LookupPath(doc, p.MetricRegistryPath)
+		if err != nil {
+			return nil, fmt.Errorf("dropwizard metric registry path %q: %s",
+				p.MetricRegistryPath, err)
+		}
+	}
+
+	registry, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dropwizard metric registry is not an object")
+	}
+	return registry, nil
+}
+
+// newMetric returns the metric called name, or nil when its object holds no
+// usable value.
+func (p *DropwizardParser) newMetric(
+	name string,
+	values map[string]interface{},
+	valueType telegraf.ValueType,
+	t time.Time,
+) (telegraf.Metric, error) {
+	measurement := name
+	tags := make(map[string]string)
+	prefix := ""
+	if p.templateEngine != nil {
+		var err error
+		measurement, tags, prefix, err = p.templateEngine.ApplyTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		if measurement == "" {
+			measurement = name
+		}
+		if prefix != "" {
+			prefix += "_"
+		}
+	}
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	fields := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case float64, string, bool:
+			fields[prefix+key] = v
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(measurement, tags, fields, t, valueType)
+}
+
+func (p *DropwizardParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: dropwizard", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *DropwizardParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,155 @@
+package dropwizard
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validRegistry = `
+{
+  "version": "3.0.0",
+  "gauges": {
+    "jvm.memory.heap.used": {"value": 123456},
+    "jvm.threads.deadlocks": {"value": []}
+  },
+  "counters": {
+    "requests.active": {"count": 3}
+  },
+  "histograms": {
+    "response.sizes": {
+      "count": 10, "max": 512, "mean": 256.5, "min": 1,
+      "p50": 250, "p75": 300, "p95": 480, "p98": 500, "p99": 510, "p999": 512,
+      "stddev": 42.1
+    }
+  },
+  "meters": {
+    "requests": {
+      "count": 42, "m15_rate": 0.5, "m1_rate": 1.5, "m5_rate": 1,
+      "mean_rate": 0.8, "units": "events/second"
+    }
+  },
+  "timers": {
+    "db.queries": {
+      "count": 5, "max": 0.2, "mean": 0.1, "min": 0.01, "p50": 0.1,
+      "stddev": 0.05, "m1_rate": 0.3, "duration_units": "seconds",
+      "rate_units": "calls/second"
+    }
+  }
+}
+`
+
+func TestParseRegistry(t *testing.T) {
+	parser := &DropwizardParser{DefaultTags: map[string]string{"host": "server01"}}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(validRegistry))
+	require.NoError(t, err)
+	require.Len(t, metrics, 5)
+
+	assert.Equal(t, "jvm.memory.heap.used", metrics[0].Name())
+	assert.Equal(t, telegraf.Gauge, metrics[0].Type())
+	assert.Equal(t, map[string]interface{}{"value": float64(123456)}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{"host": "server01"}, metrics[0].Tags())
+
+	assert.Equal(t, "requests.active", metrics[1].Name())
+	assert.Equal(t, telegraf.Counter, metrics[1].Type())
+	assert.Equal(t, map[string]interface{}{"count": float64(3)}, metrics[1].Fields())
+
+	assert.Equal(t, "response.sizes", metrics[2].Name())
+	assert.Equal(t, telegraf.Summary, metrics[2].Type())
+	assert.Equal(t, float64(480), metrics[2].Fields()["p95"])
+	assert.Len(t, metrics[2].Fields(), 11)
+
+	assert.Equal(t, "requests", metrics[3].Name())
+	assert.Equal(t, telegraf.Untyped, metrics[3].Type())
+	assert.Equal(t, "events/second", metrics[3].Fields()["units"])
+	assert.Equal(t, float64(1.5), metrics[3].Fields()["m1_rate"])
+
+	assert.Equal(t, "db.queries", metrics[4].Name())
+	assert.Equal(t, telegraf.Summary, metrics[4].Type())
+	assert.Equal(t, "seconds", metrics[4].Fields()["duration_units"])
+}
+
+func TestParseTemplates(t *testing.T) {
+	parser := &DropwizardParser{
+		Templates: []string{
+			"jvm.* measurement.measurement.field",
+			"requests.* measurement.field",
+			"measurement.service.measurement",
+		},
+		Separator:   "_",
+		DefaultTags: map[string]string{"host": "server01"},
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(`
+{
+  "gauges": {
+    "jvm.memory.heap": {"value": 1},
+    "db.users.queries": {"value": 2}
+  },
+  "counters": {
+    "requests.active": {"count": 3}
+  }
+}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "db_queries", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "server01", "service": "users"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": float64(2)}, metrics[0].Fields())
+
+	assert.Equal(t, "jvm_memory", metrics[1].Name())
+	assert.Equal(t, map[string]interface{}{"heap_value": float64(1)}, metrics[1].Fields())
+
+	assert.Equal(t, "requests", metrics[2].Name())
+	assert.Equal(t, map[string]interface{}{"active_count": float64(3)}, metrics[2].Fields())
+}
+
+func TestParseRegistryPath(t *testing.T) {
+	parser := &DropwizardParser{MetricRegistryPath: "services.1.metrics"}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(`
+{
+  "services": [
+    {"metrics": {"counters": {"a": {"count": 1}}}},
+    {"metrics": {"counters": {"b": {"count": 2}}}}
+  ]
+}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "b", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"count": float64(2)}, metrics[0].Fields())
+
+	parser.MetricRegistryPath = "services.5.metrics"
+	_, err = parser.Parse([]byte(`{"services": []}`))
+	assert.Error(t, err)
+
+	parser.MetricRegistryPath = "missing"
+	_, err = parser.Parse([]byte(`{"services": []}`))
+	assert.Error(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := &DropwizardParser{}
+	require.NoError(t, parser.Init())
+
+	_, err := parser.Parse([]byte(`{"gauges": `))
+	assert.Error(t, err)
+
+	_, err = parser.Parse([]byte(`[1, 2]`))
+	assert.Error(t, err)
+
+	metrics, err := parser.Parse([]byte(""))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	parser := &DropwizardParser{Templates: []string{"host.field"}}
+	assert.Error(t, parser.Init())
+}
@@ -9,13 +9,44 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 )
 
 type JSONParser struct {
-	MetricName  string
-	TagKeys     []string
+	MetricName string
+	TagKeys    []string
+	// StringFields are the (flattened) names of the string values kept as
+	// fields, they may contain globs.
+	StringFields []string
+	// Query is the path to the object or array of objects to parse, with
+	// the keys of objects and the indices of arrays separated by dots, ie
+	// "data.hosts". The whole document is parsed when empty.
+	Query string
+	// TimeKey is the key of the metric timestamp, which is parsed according
+	// to TimeFormat. Metrics are stamped with the current time when empty.
+	TimeKey string
+	// TimeFormat is either "unix", "unix_ms", "unix_us", "unix_ns", or a Go
+	// time layout.
+	TimeFormat  string
 	DefaultTags map[string]string
+
+	stringFields filter.Filter
+}
+
+// Init compiles the string fields and validates the time settings. It must be
+// called before parsing when any of them is set.
+func (p *JSONParser) Init() error {
+	var err error
+	p.stringFields, err = filter.Compile(p.StringFields)
+	if err != nil {
+		return fmt.Errorf("invalid json_string_fields: %s", err)
+	}
+	if p.TimeKey != "" && p.TimeFormat == "" {
+		return fmt.Errorf("json_time_format must be set with json_time_key")
+	}
+	return nil
 }
 
 func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
@@ -29,6 +60,9 @@ func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
 	}
 	for _, item := range jsonOut {
 		metrics, err = p.parseObject(metrics, item)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return metrics, nil
 }
@@ -52,13 +86,30 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 		delete(jsonOut, tag)
 	}
 
+	t := time.Now().UTC()
+	if p.TimeKey != "" {
+		var err error
+		t, err = p.parseTime(jsonOut[p.TimeKey])
+		if err != nil {
+			return nil, err
+		}
+		delete(jsonOut, p.TimeKey)
+	}
+
 	f := JSONFlattener{}
-	err := f.FlattenJSON("", jsonOut)
+	err := f.FullFlattenJSON("", jsonOut, p.stringFields != nil, false)
 	if err != nil {
 		return nil, err
 	}
+	if p.stringFields != nil {
+		for k, v := range f.Fields {
+			if _, ok := v.(string); ok && !p.stringFields.Match(k) {
+				delete(f.Fields, k)
+			}
+		}
+	}
 
-	metric, err := metric.New(p.MetricName, tags, f.Fields, time.Now().UTC())
+	metric, err := metric.New(p.MetricName, tags, f.Fields, t)
 
 	if err != nil {
 		return nil, err
@@ -66,12 +117,62 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 	return append(metrics, metric), nil
 }
 
+// parseTime returns the timestamp of a metric from the value of TimeKey.
+func (p *JSONParser) parseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, fmt.Errorf("JSON time key %q not found", p.TimeKey)
+	case float64:
+		return internal.ParseUnixTimestamp(v, p.TimeFormat)
+	case string:
+		return internal.ParseTimestamp(v, p.TimeFormat)
+	}
+	return time.Time{}, fmt.Errorf("JSON time key %q has unsupported type %T", p.TimeKey, v)
+}
+
+// parseQuery parses the objects found at the Query path.
+func (p *JSONParser) parseQuery(buf []byte) ([]telegraf.Metric, error) {
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse out as JSON, %s", err)
+	}
+
+	doc, err := internal.LookupPath(doc, p.Query)
+	if err != nil {
+		return nil, fmt.Errorf("JSON query %q: %s", p.Query, err)
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		return p.parseObject(metrics, v)
+	case []interface{}:
+		for i, elem := range v {
+			obj, ok := elem.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("JSON query %q: element %d is not an object", p.Query, i)
+			}
+			var err error
+			metrics, err = p.parseObject(metrics, obj)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return metrics, nil
+	}
+	return nil, fmt.Errorf("JSON query %q: result is not an object or an array", p.Query)
+}
+
 func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	buf = bytes.TrimSpace(buf)
 	if len(buf) == 0 {
 		return make([]telegraf.Metric, 0), nil
 	}
 
+	if p.Query != "" {
+		return p.parseQuery(buf)
+	}
+
 	if !isarray(buf) {
 		metrics := make([]telegraf.Metric, 0)
 		var jsonOut map[string]interface{}
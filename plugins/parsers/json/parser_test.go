@@ -2,8 +2,10 @@ package json
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -428,3 +430,131 @@ func TestParseArrayWithTagKeys(t *testing.T) {
 		"othertag": "baz",
 	}, metrics[1].Tags())
 }
+
+const validJSONQuery = `
+{
+    "status": "ok",
+    "data": {
+        "hosts": [
+            {
+                "name": "server-1",
+                "state": "running",
+                "load": 0.5,
+                "updated": "2017-11-01T10:00:00Z",
+                "disk": {"status": "healthy", "used": 40}
+            },
+            {
+                "name": "server-2",
+                "state": "stopped",
+                "load": 0,
+                "updated": "2017-11-01T10:00:05Z",
+                "disk": {"status": "failed", "used": 90}
+            }
+        ]
+    }
+}
+`
+
+func TestParseQuery(t *testing.T) {
+	parser := JSONParser{
+		MetricName:   "json_query_test",
+		TagKeys:      []string{"name"},
+		StringFields: []string{"state", "disk_*"},
+		Query:        "data.hosts",
+		TimeKey:      "updated",
+		TimeFormat:   time.RFC3339,
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(validJSONQuery))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "json_query_test", metrics[0].Name())
+	assert.Equal(t, map[string]string{"name": "server-1"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"state":       "running",
+		"load":        0.5,
+		"disk_status": "healthy",
+		"disk_used":   float64(40),
+	}, metrics[0].Fields())
+	assert.True(t, time.Date(2017, 11, 1, 10, 0, 0, 0, time.UTC).Equal(metrics[0].Time()))
+
+	assert.Equal(t, map[string]string{"name": "server-2"}, metrics[1].Tags())
+	assert.Equal(t, "failed", metrics[1].Fields()["disk_status"])
+	assert.True(t, time.Date(2017, 11, 1, 10, 0, 5, 0, time.UTC).Equal(metrics[1].Time()))
+}
+
+func TestParseQueryObject(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json_query_test",
+		Query:      "data.hosts.1.disk",
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse([]byte(validJSONQuery))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	// strings are ignored unless listed in the string fields
+	assert.Equal(t, map[string]interface{}{"used": float64(90)}, metrics[0].Fields())
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{"missing", "data.hosts.2", "data.hosts.x", "status", "data.hosts.0.name"} {
+		parser := JSONParser{MetricName: "json_query_test", Query: query}
+		require.NoError(t, parser.Init())
+		_, err := parser.Parse([]byte(validJSONQuery))
+		assert.Error(t, err, query)
+	}
+}
+
+func TestParseUnixTime(t *testing.T) {
+	tests := []struct {
+		format   string
+		json     string
+		expected time.Time
+	}{
+		{"unix", `{"value": 1, "time": 1500000000}`, time.Unix(1500000000, 0)},
+		{"unix", `{"value": 1, "time": "1500000000.5"}`, time.Unix(1500000000, 500000000)},
+		{"unix_ms", `{"value": 1, "time": 1500000000123}`, time.Unix(1500000000, 123000000)},
+		{"unix_us", `{"value": 1, "time": 1500000000123456}`, time.Unix(1500000000, 123456000)},
+		{"unix_ns", `[{"value": 1, "time": 1500000000000000000}]`, time.Unix(1500000000, 0)},
+		{"2006-01-02 15:04:05", `{"value": 1, "time": "2017-07-14 02:40:00"}`, time.Unix(1500000000, 0)},
+	}
+
+	for _, tt := range tests {
+		parser := JSONParser{
+			MetricName: "json_time_test",
+			TimeKey:    "time",
+			TimeFormat: tt.format,
+		}
+		require.NoError(t, parser.Init())
+		metrics, err := parser.Parse([]byte(tt.json))
+		require.NoError(t, err, tt.format)
+		require.Len(t, metrics, 1, tt.format)
+		assert.True(t, tt.expected.Equal(metrics[0].Time()), tt.format)
+		assert.Equal(t, map[string]interface{}{"value": float64(1)}, metrics[0].Fields())
+	}
+}
+
+func TestParseTimeErrors(t *testing.T) {
+	parser := JSONParser{MetricName: "json_time_test", TimeKey: "time"}
+	assert.Error(t, parser.Init())
+
+	tests := []struct {
+		format string
+		json   string
+	}{
+		{"unix", `{"value": 1}`},
+		{"unix", `{"value": 1, "time": "yesterday"}`},
+		{time.RFC3339, `{"value": 1, "time": 1500000000}`},
+		{time.RFC3339, `{"value": 1, "time": "2017-07-14"}`},
+		{"unix", `[{"value": 1, "time": 1}, {"value": 2, "time": true}]`},
+	}
+	for _, tt := range tests {
+		parser := JSONParser{MetricName: "json_time_test", TimeKey: "time", TimeFormat: tt.format}
+		require.NoError(t, parser.Init())
+		_, err := parser.Parse([]byte(tt.json))
+		assert.Error(t, err, tt.json)
+	}
+}
@@ -0,0 +1,184 @@
+package logfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// LogfmtParser parses lines of key=value pairs, such as:
+//
+//	level=info msg="request served" path=/api status=200 duration=0.025
+//
+// Each pair of a line becomes a field, or a tag when its key is in TagKeys.
+// Keys without a value are ignored.
+type LogfmtParser struct {
+	MetricName string
+	TagKeys    []string
+	// TimeKey is the key of the metric timestamp, which is parsed according
+	// to TimeFormat. Metrics are stamped with the current time when empty.
+	TimeKey string
+	// TimeFormat is either "unix", "unix_ms", "unix_us", "unix_ns", or a Go
+	// time layout, it defaults to RFC3339.
+	TimeFormat  string
+	DefaultTags map[string]string
+
+	tags map[string]bool
+}
+
+// Init validates the configuration of the parser, it must be called before
+// parsing.
+func (p *LogfmtParser) Init() error {
+	if p.MetricName == "" {
+		return fmt.Errorf("logfmt parser requires a metric name")
+	}
+	if p.TimeFormat == "" {
+		p.TimeFormat = time.RFC3339
+	}
+	p.tags = make(map[string]bool, len(p.TagKeys))
+	for _, key := range p.TagKeys {
+		p.tags[key] = true
+	}
+	return nil
+}
+
+// Parse returns a metric per line of buf, lines without fields are skipped.
+func (p *LogfmtParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		m, err := p.ParseLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// ParseLine returns the metric of line, or nil when line has no fields.
+func (p *LogfmtParser) ParseLine(line string) (telegraf.Metric, error) {
+	pairs, err := splitPairs(line)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	var t time.Time
+	for _, pair := range pairs {
+		switch {
+		case pair.key == p.TimeKey:
+			t, err = internal.ParseTimestamp(pair.value, p.TimeFormat)
+			if err != nil {
+				return nil, err
+			}
+		case p.tags[pair.key]:
+			tags[pair.key] = pair.value
+		case pair.quoted:
+			fields[pair.key] = pair.value
+		default:
+			fields[pair.key] = internal.InferValue(pair.value)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if p.TimeKey != "" && t.IsZero() {
+		return nil, fmt.Errorf("logfmt line has no %s key: %s", p.TimeKey, line)
+	}
+	if t.IsZero() {
+		t = time.Now().UTC()
+	}
+	return metric.New(p.MetricName, tags, fields, t)
+}
+
+func (p *LogfmtParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+type pair struct {
+	key    string
+	value  string
+	quoted bool
+}
+
+// splitPairs returns the key=value pairs of line, the values may be quoted
+// with the escape sequences of Go strings.
+func splitPairs(line string) ([]pair, error) {
+	var pairs []pair
+	for {
+		line = strings.TrimLeft(line, " \t\r\n")
+		if line == "" {
+			return pairs, nil
+		}
+
+		end := strings.IndexAny(line, "= \t")
+		if end < 0 {
+			end = len(line)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("logfmt pair without key: %q", line)
+		}
+		key := line[:end]
+		line = line[end:]
+		if line == "" || line[0] != '=' {
+			// a key without value
+			continue
+		}
+		line = line[1:]
+
+		if line != "" && line[0] == '"' {
+			end := closingQuote(line)
+			if end < 0 {
+				return nil, fmt.Errorf("logfmt value of %s has no closing quote", key)
+			}
+			value, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid logfmt value of %s: %s", key, err)
+			}
+			pairs = append(pairs, pair{key: key, value: value, quoted: true})
+			line = line[end+1:]
+			continue
+		}
+
+		end = strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		if end > 0 {
+			pairs = append(pairs, pair{key: key, value: line[:end]})
+		}
+		line = line[end:]
+	}
+}
+
+// closingQuote returns the index of the quote closing the string starting
+// s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,117 @@
+package logfmt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newParser(t *testing.T, p *LogfmtParser) *LogfmtParser {
+	p.MetricName = "logfmt"
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestParse(t *testing.T) {
+	p := newParser(t, &LogfmtParser{
+		TagKeys: []string{"level", "path"},
+	})
+	metrics, err := p.Parse([]byte(`level=info msg="request served" path=/api status=200 duration=0.025 cached=false
+level=debug retry
+level=warn msg="quote \" and\ttab" count="5" empty=
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "logfmt", metrics[0].Name())
+	assert.Equal(t, map[string]string{"level": "info", "path": "/api"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"msg":      "request served",
+		"status":   int64(200),
+		"duration": 0.025,
+		"cached":   false,
+	}, metrics[0].Fields())
+
+	// quoted values are kept as strings, keys without values are ignored
+	assert.Equal(t, map[string]string{"level": "warn"}, metrics[1].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"msg":   "quote \" and\ttab",
+		"count": "5",
+	}, metrics[1].Fields())
+}
+
+func TestParseLineInferDecimal(t *testing.T) {
+	p := newParser(t, &LogfmtParser{})
+	m, err := p.ParseLine("a=1.5e3 b=-2 c=nan d=Inf e=Infinity f=0x10 g=0x1p-2 h=.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": 1500.0,
+		"b": int64(-2),
+		"c": "nan",
+		"d": "Inf",
+		"e": "Infinity",
+		"f": "0x10",
+		"g": "0x1p-2",
+		"h": 0.5,
+	}, m.Fields())
+}
+
+func TestParseLineTime(t *testing.T) {
+	p := newParser(t, &LogfmtParser{
+		TimeKey: "ts",
+	})
+	m, err := p.ParseLine(`ts=2017-12-10T11:00:00.5+01:00 value=1`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": int64(1)}, m.Fields())
+	assert.Equal(t, time.Date(2017, time.December, 10, 10, 0, 0, 500000000, time.UTC).UnixNano(), m.UnixNano())
+
+	p = newParser(t, &LogfmtParser{
+		TimeKey:    "ts",
+		TimeFormat: "unix_ms",
+	})
+	m, err = p.ParseLine(`value=1 ts=1512900000500`)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2017, time.December, 10, 10, 0, 0, 500000000, time.UTC).UnixNano(), m.UnixNano())
+
+	_, err = p.ParseLine(`value=1`)
+	assert.Error(t, err)
+	_, err = p.ParseLine(`value=1 ts=yesterday`)
+	assert.Error(t, err)
+}
+
+func TestParseLineDefaultTags(t *testing.T) {
+	p := newParser(t, &LogfmtParser{
+		TagKeys: []string{"host"},
+	})
+	p.SetDefaultTags(map[string]string{"host": "default", "dc": "east"})
+	m, err := p.ParseLine(`host=server01 value=1`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "server01", "dc": "east"}, m.Tags())
+}
+
+func TestParseLineWithoutFields(t *testing.T) {
+	p := newParser(t, &LogfmtParser{
+		TagKeys: []string{"level"},
+	})
+	m, err := p.ParseLine(`level=info`)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = p.ParseLine(``)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := newParser(t, &LogfmtParser{})
+	for _, line := range []string{
+		`msg="unterminated`,
+		`=value`,
+		`msg="bad \q escape"`,
+	} {
+		_, err := p.ParseLine(line)
+		assert.Error(t, err, line)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"math"
 	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -21,8 +22,51 @@ import (
 	"github.com/prometheus/common/expfmt"
 )
 
+// PrometheusParser parses the Prometheus text exposition format.
+type PrometheusParser struct {
+	DefaultTags map[string]string
+}
+
+// Parse returns the metrics of all the metric families in buf.
+func (p *PrometheusParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics, err := Parse(buf, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single sample, which is untyped since the line holds no
+// # TYPE comment. Comments and empty lines return a nil metric.
+func (p *PrometheusParser) ParseLine(line string) (telegraf.Metric, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil, nil
+	}
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: prometheus", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *PrometheusParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
 // Parse returns a slice of Metrics from a text representation of a
-// metrics
+// metrics, or from delimited protocol buffers when the Content-Type of header
+// says so.
 func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 	var metrics []telegraf.Metric
 	var parser expfmt.TextParser
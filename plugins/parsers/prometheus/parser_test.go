@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -163,3 +164,29 @@ func TestParseValidPrometheus(t *testing.T) {
 		metrics[0].Tags())
 
 }
+
+func TestPrometheusParserDefaultTags(t *testing.T) {
+	parser := &PrometheusParser{
+		DefaultTags: map[string]string{"host": "localhost", "handler": "default"},
+	}
+	metrics, err := parser.Parse([]byte(validUniqueSummary))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, telegraf.Summary, metrics[0].Type())
+	assert.Equal(t,
+		map[string]string{"host": "localhost", "handler": "prometheus"},
+		metrics[0].Tags())
+}
+
+func TestPrometheusParserParseLine(t *testing.T) {
+	parser := &PrometheusParser{}
+	m, err := parser.ParseLine(`get_token_fail_count{host="a"} 3`)
+	assert.NoError(t, err)
+	assert.Equal(t, "get_token_fail_count", m.Name())
+	assert.Equal(t, map[string]interface{}{"value": float64(3)}, m.Fields())
+	assert.Equal(t, map[string]string{"host": "a"}, m.Tags())
+
+	m, err = parser.ParseLine("# TYPE get_token_fail_count counter")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+}
@@ -0,0 +1,44 @@
+package protobuf
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/protobuf"
+)
+
+// ProtobufParser reads the length-delimited Metric messages written by the
+// protobuf serializer, see internal/protobuf/metric.proto.
+type ProtobufParser struct {
+	DefaultTags map[string]string
+}
+
+func (p *ProtobufParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics, err := protobuf.UnmarshalDelimited(buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine is not supported, as messages are binary and may hold newlines.
+func (p *ProtobufParser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, fmt.Errorf("the protobuf data format can not be parsed line by line")
+}
+
+func (p *ProtobufParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// Split is a bufio.SplitFunc returning the length-delimited messages of a
+// stream, for the inputs reading streams such as socket_listener.
+func (p *ProtobufParser) Split(data []byte, atEOF bool) (int, []byte, error) {
+	return protobuf.SplitDelimited(data, atEOF)
+}
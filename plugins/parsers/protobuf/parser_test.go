@@ -0,0 +1,100 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/protobuf"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	now := time.Unix(0, time.Now().UnixNano())
+	m1, err := metric.New(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage": float64(91.5),
+			"count": int64(-42),
+			"ok":    true,
+			"state": "running",
+		},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	m2, err := metric.New(
+		"latency",
+		map[string]string{},
+		map[string]interface{}{"0.5": float64(0.1), "count": int64(10)},
+		now,
+		telegraf.Summary,
+	)
+	require.NoError(t, err)
+
+	s := &protobuf.ProtobufSerializer{}
+	var buf []byte
+	for _, m := range []telegraf.Metric{m1, m2} {
+		b, err := s.Serialize(m)
+		require.NoError(t, err)
+		buf = append(buf, b...)
+	}
+
+	parser := &ProtobufParser{DefaultTags: map[string]string{"host": "b", "dc": "us"}}
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "cpu", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "a", "cpu": "cpu0", "dc": "us"}, metrics[0].Tags())
+	assert.Equal(t, m1.Fields(), metrics[0].Fields())
+	assert.Equal(t, telegraf.Counter, metrics[0].Type())
+	assert.True(t, now.Equal(metrics[0].Time()))
+
+	assert.Equal(t, "latency", metrics[1].Name())
+	assert.Equal(t, map[string]string{"host": "b", "dc": "us"}, metrics[1].Tags())
+	assert.Equal(t, m2.Fields(), metrics[1].Fields())
+	assert.Equal(t, telegraf.Summary, metrics[1].Type())
+}
+
+func TestParseSkipsUnknownFields(t *testing.T) {
+	buf := []byte{
+		24,
+		0x0a, 1, 'm',
+		// unknown fixed32 field 6
+		0x35, 1, 2, 3, 4,
+		// field with an unknown value type
+		0x1a, 5, 0x0a, 1, 'x', 0x30, 1,
+		0x1a, 5, 0x0a, 1, 'v', 0x18, 2,
+		0x20, 0,
+	}
+	parser := &ProtobufParser{}
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"v": int64(1)}, metrics[0].Fields())
+	assert.Equal(t, telegraf.Untyped, metrics[0].Type())
+}
+
+func TestParseTruncated(t *testing.T) {
+	parser := &ProtobufParser{}
+	_, err := parser.Parse([]byte{12, 0x0a, 1, 'm'})
+	assert.Error(t, err)
+
+	_, err = parser.Parse([]byte{4, 0x0a, 5, 'm', 'e'})
+	assert.Error(t, err)
+
+	metrics, err := parser.Parse([]byte{})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
+
+func TestParseLineNotSupported(t *testing.T) {
+	parser := &ProtobufParser{}
+	_, err := parser.ParseLine("cpu")
+	assert.Error(t, err)
+}
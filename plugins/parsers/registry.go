@@ -6,10 +6,15 @@ import (
 	"github.com/influxdata/telegraf"
 
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
+	"github.com/influxdata/telegraf/plugins/parsers/csv"
+	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 )
 
@@ -20,6 +25,17 @@ type ParserInput interface {
 	SetParser(parser Parser)
 }
 
+// ParserFunc creates a new parser.
+type ParserFunc func() (Parser, error)
+
+// ParserFuncInput is an interface for input plugins that are able to parse
+// arbitrary data formats, and need a parser for each of their sources, as
+// parsers may keep state between lines.
+type ParserFuncInput interface {
+	// SetParserFunc sets the function creating the parsers of the input
+	SetParserFunc(fn ParserFunc)
+}
+
 // Parser is an interface defining functions that a parser plugin must satisfy.
 type Parser interface {
 	// Parse takes a byte buffer separated by newlines
@@ -41,16 +57,25 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, influx, graphite, value, nagios,
+	// collectd, csv, prometheus, dropwizard, protobuf, logfmt
 	DataFormat string
 
-	// Separator only applied to Graphite data.
+	// Separator only applied to Graphite and Dropwizard data.
 	Separator string
-	// Templates only apply to Graphite data.
+	// Templates only apply to Graphite and Dropwizard data.
 	Templates []string
 
-	// TagKeys only apply to JSON data
+	// TagKeys only apply to JSON and logfmt data
 	TagKeys []string
+	// JSONStringFields are the names of the string values kept as fields.
+	JSONStringFields []string
+	// JSONQuery is the path to the objects to parse in JSON data.
+	JSONQuery string
+	// JSONTimeKey is the key of the timestamp in JSON data, which is parsed
+	// according to JSONTimeFormat.
+	JSONTimeKey    string
+	JSONTimeFormat string
 	// MetricName applies to JSON & value. This will be the name of the measurement.
 	MetricName string
 
@@ -64,6 +89,29 @@ type Config struct {
 	// DataType only applies to value, this will be the type to parse value to
 	DataType string
 
+	// CSV options, see the csv.CSVParser fields of the same names
+	CSVHeaderRowCount    int
+	CSVSkipRows          int
+	CSVSkipColumns       int
+	CSVColumnNames       []string
+	CSVColumnTypes       []string
+	CSVDelimiter         string
+	CSVComment           string
+	CSVTrimSpace         bool
+	CSVTagColumns        []string
+	CSVMeasurementColumn string
+	CSVTimestampColumn   string
+	CSVTimestampFormat   string
+
+	// DropwizardMetricRegistryPath is the path to the metric registry in
+	// Dropwizard data.
+	DropwizardMetricRegistryPath string
+
+	// LogfmtTimeKey is the key of the timestamp in logfmt data, which is
+	// parsed according to LogfmtTimeFormat.
+	LogfmtTimeKey    string
+	LogfmtTimeFormat string
+
 	// DefaultTags are the default tags that will be added to all parsed metrics.
 	DefaultTags map[string]string
 }
@@ -74,8 +122,7 @@ func NewParser(config *Config) (Parser, error) {
 	var parser Parser
 	switch config.DataFormat {
 	case "json":
-		parser, err = NewJSONParser(config.MetricName,
-			config.TagKeys, config.DefaultTags)
+		parser, err = newJSONParser(config)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -89,6 +136,16 @@ func NewParser(config *Config) (Parser, error) {
 	case "collectd":
 		parser, err = NewCollectdParser(config.CollectdAuthFile,
 			config.CollectdSecurityLevel, config.CollectdTypesDB)
+	case "csv":
+		parser, err = newCSVParser(config)
+	case "prometheus":
+		parser, err = NewPrometheusParser(config.DefaultTags)
+	case "dropwizard":
+		parser, err = newDropwizardParser(config)
+	case "protobuf":
+		parser, err = NewProtobufParser(config.DefaultTags)
+	case "logfmt":
+		parser, err = newLogfmtParser(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -108,10 +165,34 @@ func NewJSONParser(
 	return parser, nil
 }
 
+func newJSONParser(config *Config) (Parser, error) {
+	parser := &json.JSONParser{
+		MetricName:   config.MetricName,
+		TagKeys:      config.TagKeys,
+		StringFields: config.JSONStringFields,
+		Query:        config.JSONQuery,
+		TimeKey:      config.JSONTimeKey,
+		TimeFormat:   config.JSONTimeFormat,
+		DefaultTags:  config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
 func NewNagiosParser() (Parser, error) {
 	return &nagios.NagiosParser{}, nil
 }
 
+func NewPrometheusParser(defaultTags map[string]string) (Parser, error) {
+	return &prometheus.PrometheusParser{DefaultTags: defaultTags}, nil
+}
+
+func NewProtobufParser(defaultTags map[string]string) (Parser, error) {
+	return &protobuf.ProtobufParser{DefaultTags: defaultTags}, nil
+}
+
 func NewInfluxParser() (Parser, error) {
 	return &influx.InfluxParser{}, nil
 }
@@ -143,3 +224,53 @@ func NewCollectdParser(
 ) (Parser, error) {
 	return collectd.NewCollectdParser(authFile, securityLevel, typesDB)
 }
+
+func newCSVParser(config *Config) (Parser, error) {
+	parser := &csv.CSVParser{
+		MetricName:        config.MetricName,
+		HeaderRowCount:    config.CSVHeaderRowCount,
+		SkipRows:          config.CSVSkipRows,
+		SkipColumns:       config.CSVSkipColumns,
+		ColumnNames:       config.CSVColumnNames,
+		ColumnTypes:       config.CSVColumnTypes,
+		Delimiter:         config.CSVDelimiter,
+		Comment:           config.CSVComment,
+		TrimSpace:         config.CSVTrimSpace,
+		TagColumns:        config.CSVTagColumns,
+		MeasurementColumn: config.CSVMeasurementColumn,
+		TimestampColumn:   config.CSVTimestampColumn,
+		TimestampFormat:   config.CSVTimestampFormat,
+		DefaultTags:       config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func newDropwizardParser(config *Config) (Parser, error) {
+	parser := &dropwizard.DropwizardParser{
+		MetricRegistryPath: config.DropwizardMetricRegistryPath,
+		Templates:          config.Templates,
+		Separator:          config.Separator,
+		DefaultTags:        config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func newLogfmtParser(config *Config) (Parser, error) {
+	parser := &logfmt.LogfmtParser{
+		MetricName:  config.MetricName,
+		TagKeys:     config.TagKeys,
+		TimeKey:     config.LogfmtTimeKey,
+		TimeFormat:  config.LogfmtTimeFormat,
+		DefaultTags: config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
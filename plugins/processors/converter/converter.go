@@ -0,0 +1,311 @@
+package converter
+
+import (
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tags to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<tag-key>...]
+  [processors.converter.tags]
+    string = []
+    integer = []
+    boolean = []
+    float = []
+
+  ## Fields to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<field-key>...]
+  [processors.converter.fields]
+    tag = []
+    string = []
+    integer = []
+    boolean = []
+    float = []
+`
+
+// Conversion lists the keys to convert to each type.
+type Conversion struct {
+	Tag     []string `toml:"tag"`
+	String  []string `toml:"string"`
+	Integer []string `toml:"integer"`
+	Boolean []string `toml:"boolean"`
+	Float   []string `toml:"float"`
+}
+
+type Converter struct {
+	Tags   *Conversion `toml:"tags"`
+	Fields *Conversion `toml:"fields"`
+
+	tagConversions   *conversionFilter
+	fieldConversions *conversionFilter
+}
+
+type conversionFilter struct {
+	Tag     filter.Filter
+	String  filter.Filter
+	Integer filter.Filter
+	Boolean filter.Filter
+	Float   filter.Filter
+}
+
+func (p *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+func (p *Converter) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		out = append(out, p.convert(m))
+	}
+	return out
+}
+
+// Init compiles the key filters, so that invalid globs are reported when the
+// configuration is loaded.
+func (p *Converter) Init() error {
+	tf, err := compileFilter(p.Tags)
+	if err != nil {
+		return err
+	}
+
+	ff, err := compileFilter(p.Fields)
+	if err != nil {
+		return err
+	}
+
+	p.tagConversions = tf
+	p.fieldConversions = ff
+	return nil
+}
+
+func compileFilter(conv *Conversion) (*conversionFilter, error) {
+	if conv == nil {
+		return nil, nil
+	}
+
+	var err error
+	cf := &conversionFilter{}
+	cf.Tag, err = filter.Compile(conv.Tag)
+	if err != nil {
+		return nil, err
+	}
+	cf.String, err = filter.Compile(conv.String)
+	if err != nil {
+		return nil, err
+	}
+	cf.Integer, err = filter.Compile(conv.Integer)
+	if err != nil {
+		return nil, err
+	}
+	cf.Boolean, err = filter.Compile(conv.Boolean)
+	if err != nil {
+		return nil, err
+	}
+	cf.Float, err = filter.Compile(conv.Float)
+	if err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+func match(f filter.Filter, key string) bool {
+	return f != nil && f.Match(key)
+}
+
+// convert converts the tags and fields of m; values that cannot be converted
+// are left as they are.
+func (p *Converter) convert(m telegraf.Metric) telegraf.Metric {
+	origTags := m.Tags()
+	origFields := m.Fields()
+	tags := m.Tags()
+	fields := m.Fields()
+
+	var changed bool
+	if cf := p.tagConversions; cf != nil {
+		for key, value := range origTags {
+			var v interface{}
+			var ok bool
+			switch {
+			case match(cf.String, key):
+				v, ok = value, true
+			case match(cf.Integer, key):
+				v, ok = toInteger(value)
+			case match(cf.Boolean, key):
+				v, ok = toBool(value)
+			case match(cf.Float, key):
+				v, ok = toFloat(value)
+			default:
+				continue
+			}
+			if !ok {
+				log.Printf("D! [processors.converter] unable to convert tag %q "+
+					"value %q", key, value)
+				continue
+			}
+			delete(tags, key)
+			fields[key] = v
+			changed = true
+		}
+	}
+
+	if cf := p.fieldConversions; cf != nil {
+		for key, value := range origFields {
+			var v interface{}
+			var ok bool
+			switch {
+			case match(cf.Tag, key):
+				v, ok = toString(value)
+			case match(cf.String, key):
+				v, ok = toString(value)
+			case match(cf.Integer, key):
+				v, ok = toInteger(value)
+			case match(cf.Boolean, key):
+				v, ok = toBool(value)
+			case match(cf.Float, key):
+				v, ok = toFloat(value)
+			default:
+				continue
+			}
+			if !ok {
+				log.Printf("D! [processors.converter] unable to convert field %q "+
+					"value %v", key, value)
+				continue
+			}
+			if match(cf.Tag, key) {
+				delete(fields, key)
+				tags[key] = v.(string)
+			} else {
+				fields[key] = v
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return m
+	}
+
+	converted, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+	if err != nil {
+		log.Printf("E! [processors.converter] could not convert metric %s: %s",
+			m.Name(), err)
+		return m
+	}
+	converted.SetAggregate(m.IsAggregate())
+	return metric.WithTrackingFrom(m, converted)
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		if value > math.MaxInt64 {
+			return int64(math.MaxInt64), true
+		}
+		return int64(value), true
+	case float64:
+		if value < float64(math.MinInt64) || value > float64(math.MaxInt64) {
+			return nil, false
+		}
+		return int64(value), true
+	case bool:
+		if value {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		result, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// accept floats written as integers, ie "42.0"
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false
+			}
+			return toInteger(f)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return float64(1), true
+		}
+		return float64(0), true
+	case string:
+		result, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func toBool(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value != 0, true
+	case uint64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case bool:
+		return value, true
+	case string:
+		result, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, false
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func toString(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case string:
+		return value, true
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,160 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("cpu", tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		name           string
+		converter      *Converter
+		input          telegraf.Metric
+		expectedTags   map[string]string
+		expectedFields map[string]interface{}
+	}{
+		{
+			name: "from tag",
+			converter: &Converter{
+				Tags: &Conversion{
+					String:  []string{"string"},
+					Integer: []string{"int*"},
+					Boolean: []string{"bool"},
+					Float:   []string{"float"},
+				},
+			},
+			input: newMetric(
+				map[string]string{
+					"string": "howdy",
+					"int":    "42",
+					"int_f":  "42.0",
+					"bool":   "true",
+					"float":  "4.2",
+					"other":  "x",
+				},
+				map[string]interface{}{"value": 42.0},
+			),
+			expectedTags: map[string]string{
+				"other": "x",
+			},
+			expectedFields: map[string]interface{}{
+				"value":  42.0,
+				"string": "howdy",
+				"int":    int64(42),
+				"int_f":  int64(42),
+				"bool":   true,
+				"float":  4.2,
+			},
+		},
+		{
+			name: "from field",
+			converter: &Converter{
+				Fields: &Conversion{
+					Tag:     []string{"host"},
+					String:  []string{"a"},
+					Integer: []string{"b", "c"},
+					Boolean: []string{"d", "e"},
+					Float:   []string{"f", "g"},
+				},
+			},
+			input: newMetric(
+				map[string]string{},
+				map[string]interface{}{
+					"host": "localhost",
+					"a":    int64(42),
+					"b":    "42",
+					"c":    4.9,
+					"d":    int64(0),
+					"e":    "true",
+					"f":    int64(42),
+					"g":    "4.2",
+				},
+			),
+			expectedTags: map[string]string{
+				"host": "localhost",
+			},
+			expectedFields: map[string]interface{}{
+				"a": "42",
+				"b": int64(42),
+				"c": int64(4),
+				"d": false,
+				"e": true,
+				"f": 42.0,
+				"g": 4.2,
+			},
+		},
+		{
+			name: "invalid values are unchanged",
+			converter: &Converter{
+				Tags: &Conversion{
+					Integer: []string{"a"},
+				},
+				Fields: &Conversion{
+					Boolean: []string{"b"},
+					Float:   []string{"c"},
+				},
+			},
+			input: newMetric(
+				map[string]string{"a": "not a number"},
+				map[string]interface{}{
+					"b": "maybe",
+					"c": "4.2.1",
+				},
+			),
+			expectedTags: map[string]string{
+				"a": "not a number",
+			},
+			expectedFields: map[string]interface{}{
+				"b": "maybe",
+				"c": "4.2.1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.converter.Init())
+			metrics := tt.converter.Apply(tt.input)
+
+			require.Len(t, metrics, 1)
+			assert.Equal(t, "cpu", metrics[0].Name())
+			assert.Equal(t, tt.expectedTags, metrics[0].Tags())
+			assert.Equal(t, tt.expectedFields, metrics[0].Fields())
+		})
+	}
+}
+
+func TestConverterAllFieldsToTags(t *testing.T) {
+	converter := &Converter{
+		Fields: &Conversion{
+			Tag: []string{"*"},
+		},
+	}
+	require.NoError(t, converter.Init())
+	m := newMetric(map[string]string{}, map[string]interface{}{"host": "localhost"})
+
+	// a metric needs at least one field, so it is left as is
+	metrics := converter.Apply(m)
+	require.Len(t, metrics, 1)
+	assert.True(t, m == metrics[0])
+}
+
+func TestConverterInvalidGlob(t *testing.T) {
+	converter := &Converter{
+		Fields: &Conversion{
+			Integer: []string{"a[b"},
+		},
+	}
+	assert.Error(t, converter.Init())
+}
@@ -0,0 +1,114 @@
+package enum
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  # [[processors.enum.mapping]]
+  #   ## Name of the field to map
+  #   field = "status"
+  #
+  #   ## Destination field to be used for the mapped value.  By default the
+  #   ## source field is used, overwriting the original value.
+  #   # dest = "status_code"
+  #
+  #   ## Default value to be used for all values not contained in the mapping
+  #   ## table.  When unset, the unmodified value for the field will be used if
+  #   ## no match is found.
+  #   # default = 0
+  #
+  #   ## Table of mappings
+  #   [processors.enum.mapping.value_mappings]
+  #     green = 1
+  #     yellow = 2
+  #     red = 3
+`
+
+// Mapping maps the string values of a field to other values.
+type Mapping struct {
+	Field         string                 `toml:"field"`
+	Dest          string                 `toml:"dest"`
+	Default       interface{}            `toml:"default"`
+	ValueMappings map[string]interface{} `toml:"value_mappings"`
+}
+
+type EnumMapper struct {
+	Mappings []Mapping `toml:"mapping"`
+}
+
+func (mapper *EnumMapper) SampleConfig() string {
+	return sampleConfig
+}
+
+func (mapper *EnumMapper) Description() string {
+	return "Map enum values according to given table."
+}
+
+func (mapper *EnumMapper) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		out = append(out, mapper.applyMappings(m))
+	}
+	return out
+}
+
+func (mapper *EnumMapper) applyMappings(m telegraf.Metric) telegraf.Metric {
+	fields := m.Fields()
+
+	var changed bool
+	for _, mapping := range mapper.Mappings {
+		value, ok := fields[mapping.Field]
+		if !ok {
+			continue
+		}
+		// only string values are mapped
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if mapped, ok := mapping.mapValue(str); ok {
+			fields[mapping.destination()] = mapped
+			changed = true
+		}
+	}
+	if !changed {
+		return m
+	}
+
+	mapped, err := metric.New(m.Name(), m.Tags(), fields, m.Time(), m.Type())
+	if err != nil {
+		log.Printf("E! [processors.enum] could not map metric %s: %s",
+			m.Name(), err)
+		return m
+	}
+	mapped.SetAggregate(m.IsAggregate())
+	return metric.WithTrackingFrom(m, mapped)
+}
+
+func (mapping *Mapping) mapValue(original string) (interface{}, bool) {
+	if mapped, found := mapping.ValueMappings[original]; found {
+		return mapped, true
+	}
+	if mapping.Default != nil {
+		return mapping.Default, true
+	}
+	return nil, false
+}
+
+func (mapping *Mapping) destination() string {
+	if mapping.Dest != "" {
+		return mapping.Dest
+	}
+	return mapping.Field
+}
+
+func init() {
+	processors.Add("enum", func() telegraf.Processor {
+		return &EnumMapper{}
+	})
+}
@@ -0,0 +1,157 @@
+package regex
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tag and field conversions defined in a separate sub-tables
+  # [[processors.regex.tags]]
+  #   ## Tag to change
+  #   key = "resp_code"
+  #   ## Regular expression to match on a tag value
+  #   pattern = "^(\\d)\\d\\d$"
+  #   ## Pattern for constructing a new value (${1} represents first subgroup)
+  #   replacement = "${1}xx"
+
+  # [[processors.regex.fields]]
+  #   key = "request"
+  #   ## All the power of the Go regular expressions available here
+  #   ## For example, named subgroups
+  #   pattern = "^/api(?P<method>/[\\w/]+)\\S*"
+  #   replacement = "${method}"
+  #   ## If result_key is present, a new field will be created
+  #   ## instead of changing existing field
+  #   result_key = "method"
+
+  ## Multiple conversions may be applied for one field sequentially
+  ## Let's extract one more value
+  # [[processors.regex.fields]]
+  #   key = "request"
+  #   pattern = ".*category=(\\w+).*"
+  #   replacement = "${1}"
+  #   result_key = "search_category"
+`
+
+// Converter replaces the value of a tag or a string field matching Pattern.
+type Converter struct {
+	Key         string `toml:"key"`
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+	ResultKey   string `toml:"result_key"`
+}
+
+type Regex struct {
+	Tags   []Converter `toml:"tags"`
+	Fields []Converter `toml:"fields"`
+
+	regexCache map[string]*regexp.Regexp
+}
+
+func NewRegex() *Regex {
+	return &Regex{
+		regexCache: make(map[string]*regexp.Regexp),
+	}
+}
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transforms tag and field values with regex pattern"
+}
+
+// Init compiles the patterns, so that invalid ones are reported when the
+// configuration is loaded.
+func (r *Regex) Init() error {
+	for _, converters := range [][]Converter{r.Tags, r.Fields} {
+		for _, c := range converters {
+			if _, ok := r.regexCache[c.Pattern]; ok {
+				continue
+			}
+			regex, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %s", c.Pattern, err)
+			}
+			r.regexCache[c.Pattern] = regex
+		}
+	}
+	return nil
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		out = append(out, r.apply(m))
+	}
+	return out
+}
+
+func (r *Regex) apply(m telegraf.Metric) telegraf.Metric {
+	tags := m.Tags()
+	fields := m.Fields()
+
+	var changed bool
+	for _, converter := range r.Tags {
+		value, ok := tags[converter.Key]
+		if !ok {
+			continue
+		}
+		if key, newValue, ok := r.convert(converter, value); ok {
+			tags[key] = newValue
+			changed = true
+		}
+	}
+
+	for _, converter := range r.Fields {
+		// only string fields can be transformed
+		value, ok := fields[converter.Key].(string)
+		if !ok {
+			continue
+		}
+		if key, newValue, ok := r.convert(converter, value); ok {
+			fields[key] = newValue
+			changed = true
+		}
+	}
+	if !changed {
+		return m
+	}
+
+	converted, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+	if err != nil {
+		log.Printf("E! [processors.regex] could not transform metric %s: %s",
+			m.Name(), err)
+		return m
+	}
+	converted.SetAggregate(m.IsAggregate())
+	return metric.WithTrackingFrom(m, converted)
+}
+
+// convert returns the key and the replaced value for a value matching the
+// pattern of the converter.
+func (r *Regex) convert(c Converter, value string) (string, string, bool) {
+	regex := r.regexCache[c.Pattern]
+	if !regex.MatchString(value) {
+		return "", "", false
+	}
+
+	key := c.Key
+	if c.ResultKey != "" {
+		key = c.ResultKey
+	}
+	return key, regex.ReplaceAllString(value, c.Replacement), true
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return NewRegex()
+	})
+}
@@ -0,0 +1,103 @@
+package rename
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Renames are applied in the order they are defined, each one renames
+  ## either a measurement, a tag or a field.
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+
+  # [[processors.rename.replace]]
+  #   field = "lower"
+  #   dest = "min"
+`
+
+// Replace renames the measurement, tag or field it names to Dest.
+type Replace struct {
+	Measurement string `toml:"measurement"`
+	Tag         string `toml:"tag"`
+	Field       string `toml:"field"`
+	Dest        string `toml:"dest"`
+}
+
+type Rename struct {
+	Replaces []Replace `toml:"replace"`
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		out = append(out, r.rename(m))
+	}
+	return out
+}
+
+func (r *Rename) rename(m telegraf.Metric) telegraf.Metric {
+	name := m.Name()
+	tags := m.Tags()
+	fields := m.Fields()
+
+	var changed bool
+	for _, replace := range r.Replaces {
+		if replace.Dest == "" {
+			continue
+		}
+		switch {
+		case replace.Measurement != "":
+			if name == replace.Measurement {
+				name = replace.Dest
+				changed = true
+			}
+		case replace.Tag != "":
+			if value, ok := tags[replace.Tag]; ok {
+				delete(tags, replace.Tag)
+				tags[replace.Dest] = value
+				changed = true
+			}
+		case replace.Field != "":
+			if value, ok := fields[replace.Field]; ok {
+				delete(fields, replace.Field)
+				fields[replace.Dest] = value
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return m
+	}
+
+	renamed, err := metric.New(name, tags, fields, m.Time(), m.Type())
+	if err != nil {
+		log.Printf("E! [processors.rename] could not rename metric %s: %s",
+			m.Name(), err)
+		return m
+	}
+	renamed.SetAggregate(m.IsAggregate())
+	return metric.WithTrackingFrom(m, renamed)
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
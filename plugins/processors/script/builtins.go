@@ -0,0 +1,400 @@
+package script
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type builtin struct {
+	name string
+	// minArgs and maxArgs bound the number of arguments, a negative maxArgs
+	// does not limit it.
+	minArgs, maxArgs int
+	fn               func(in *interp, args []interface{}) (interface{}, error)
+}
+
+// builtins are the only functions a script can call. None of them give
+// access to the file system, the network or the rest of the agent.
+var builtins = map[string]*builtin{}
+
+func init() {
+	for _, b := range []*builtin{
+		{"len", 1, 1, builtinLen},
+		{"type", 1, 1, func(in *interp, args []interface{}) (interface{}, error) {
+			return typeName(args[0]), nil
+		}},
+		{"int", 1, 1, builtinInt},
+		{"float", 1, 1, builtinFloat},
+		{"string", 1, 1, func(in *interp, args []interface{}) (interface{}, error) {
+			return toString(args[0]), nil
+		}},
+		{"bool", 1, 1, builtinBool},
+
+		{"lower", 1, 1, stringFunc(strings.ToLower)},
+		{"upper", 1, 1, stringFunc(strings.ToUpper)},
+		{"trim", 1, 1, stringFunc(strings.TrimSpace)},
+		{"startswith", 2, 2, builtinStartsWith},
+		{"endswith", 2, 2, builtinEndsWith},
+		{"replace", 3, 3, builtinReplace},
+		{"split", 2, 2, builtinSplit},
+		{"join", 2, 2, builtinJoin},
+
+		{"abs", 1, 1, mathFunc(math.Abs)},
+		{"ceil", 1, 1, mathFunc(math.Ceil)},
+		{"floor", 1, 1, mathFunc(math.Floor)},
+		{"round", 1, 1, mathFunc(round)},
+		{"sqrt", 1, 1, mathFunc(math.Sqrt)},
+		{"log", 1, 1, mathFunc(math.Log)},
+		{"pow", 2, 2, builtinPow},
+		{"min", 1, -1, builtinMin},
+		{"max", 1, -1, builtinMax},
+
+		{"keys", 1, 1, builtinKeys},
+		{"delete", 2, 2, builtinDelete},
+		{"now", 0, 0, func(in *interp, args []interface{}) (interface{}, error) {
+			return time.Now().UnixNano(), nil
+		}},
+		{"drop", 0, 0, func(in *interp, args []interface{}) (interface{}, error) {
+			in.dropped = true
+			return nil, nil
+		}},
+		{"emit", 3, 4, builtinEmit},
+	} {
+		builtins[b.name] = b
+	}
+}
+
+func builtinLen(in *interp, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		return int64(len(v)), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case *Map:
+		return int64(len(v.values)), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func builtinInt(in *interp, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || v < math.MinInt64 || v > math.MaxInt64 {
+			return nil, fmt.Errorf("%v out of range", v)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		return builtinInt(in, []interface{}{f})
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func builtinFloat(in *interp, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", v)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func builtinBool(in *interp, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		s := make([]string, len(v))
+		for i, e := range v {
+			s[i] = toString(e)
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case *Map:
+		s := make([]string, 0, len(v.values))
+		for _, k := range v.keys() {
+			s = append(s, strconv.Quote(k)+": "+toString(v.values[k]))
+		}
+		return "{" + strings.Join(s, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+func stringArgs(args []interface{}) ([]string, error) {
+	s := make([]string, len(args))
+	for i, a := range args {
+		v, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %d is %s, not string", i+1, typeName(a))
+		}
+		s[i] = v
+	}
+	return s, nil
+}
+
+func stringFunc(f func(string) string) func(*interp, []interface{}) (interface{}, error) {
+	return func(in *interp, args []interface{}) (interface{}, error) {
+		s, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return f(s[0]), nil
+	}
+}
+
+func builtinStartsWith(in *interp, args []interface{}) (interface{}, error) {
+	s, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s[0], s[1]), nil
+}
+
+func builtinEndsWith(in *interp, args []interface{}) (interface{}, error) {
+	s, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s[0], s[1]), nil
+}
+
+func builtinReplace(in *interp, args []interface{}) (interface{}, error) {
+	s, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if n := strings.Count(s[0], s[1]); n > 0 && len(s[0])+n*(len(s[2])-len(s[1])) > maxStringLen {
+		return nil, fmt.Errorf("string too long")
+	}
+	return strings.Replace(s[0], s[1], s[2], -1), nil
+}
+
+func builtinSplit(in *interp, args []interface{}) (interface{}, error) {
+	s, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s[0], s[1])
+	l := make([]interface{}, len(parts))
+	for i, p := range parts {
+		l[i] = p
+	}
+	return l, nil
+}
+
+func builtinJoin(in *interp, args []interface{}) (interface{}, error) {
+	l, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument 1 is %s, not list", typeName(args[0]))
+	}
+	sep, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("argument 2 is %s, not string", typeName(args[1]))
+	}
+	s := make([]string, len(l))
+	n := len(sep) * len(l)
+	for i, e := range l {
+		s[i] = toString(e)
+		n += len(s[i])
+	}
+	if n > maxStringLen {
+		return nil, fmt.Errorf("string too long")
+	}
+	return strings.Join(s, sep), nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func round(x float64) float64 {
+	if x < 0 {
+		return math.Ceil(x - 0.5)
+	}
+	return math.Floor(x + 0.5)
+}
+
+func mathFunc(f func(float64) float64) func(*interp, []interface{}) (interface{}, error) {
+	return func(in *interp, args []interface{}) (interface{}, error) {
+		x, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+		}
+		return f(x), nil
+	}
+}
+
+func builtinPow(in *interp, args []interface{}) (interface{}, error) {
+	x, ok := toFloat(args[0])
+	y, ok2 := toFloat(args[1])
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("invalid arguments %s and %s", typeName(args[0]), typeName(args[1]))
+	}
+	return math.Pow(x, y), nil
+}
+
+// minMax returns the smallest, or largest, of its numeric arguments, or of
+// the elements of a single list argument.
+func minMax(args []interface{}, less bool) (interface{}, error) {
+	if l, ok := args[0].([]interface{}); ok && len(args) == 1 {
+		args = l
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+
+	var result interface{}
+	var best float64
+	for i, a := range args {
+		f, ok := toFloat(a)
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %s", typeName(a))
+		}
+		if i == 0 || (less && f < best) || (!less && f > best) {
+			result, best = a, f
+		}
+	}
+	return result, nil
+}
+
+func builtinMin(in *interp, args []interface{}) (interface{}, error) {
+	return minMax(args, true)
+}
+
+func builtinMax(in *interp, args []interface{}) (interface{}, error) {
+	return minMax(args, false)
+}
+
+func builtinKeys(in *interp, args []interface{}) (interface{}, error) {
+	m, ok := args[0].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("argument is %s, not map", typeName(args[0]))
+	}
+	keys := m.keys()
+	l := make([]interface{}, len(keys))
+	for i, k := range keys {
+		l[i] = k
+	}
+	return l, nil
+}
+
+func builtinDelete(in *interp, args []interface{}) (interface{}, error) {
+	m, ok := args[0].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("argument 1 is %s, not map", typeName(args[0]))
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("argument 2 is %s, not string", typeName(args[1]))
+	}
+	delete(m.values, key)
+	return nil, nil
+}
+
+// builtinEmit adds a new metric, emit(name, tags, fields[, time]). It has
+// the time of the current metric unless one is given.
+func builtinEmit(in *interp, args []interface{}) (interface{}, error) {
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("name is %s, not string", typeName(args[0]))
+	}
+	tags, ok := args[1].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("tags are %s, not map", typeName(args[1]))
+	}
+	fields, ok := args[2].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("fields are %s, not map", typeName(args[2]))
+	}
+	t, _ := in.vars["time"].(int64)
+	if len(args) == 4 {
+		if t, ok = args[3].(int64); !ok {
+			return nil, fmt.Errorf("time is %s, not int", typeName(args[3]))
+		}
+	}
+
+	e := &emitted{
+		name:   name,
+		tags:   make(map[string]string, len(tags.values)),
+		fields: make(map[string]interface{}, len(fields.values)),
+		time:   time.Unix(0, t),
+	}
+	for k, v := range tags.values {
+		s, err := checkTag(k, v)
+		if err != nil {
+			return nil, err
+		}
+		e.tags[k] = s.(string)
+	}
+	for k, v := range fields.values {
+		f, err := checkField(k, v)
+		if err != nil {
+			return nil, err
+		}
+		e.fields[k] = f
+	}
+	if len(e.fields) == 0 {
+		return nil, fmt.Errorf("metric %q has no fields", name)
+	}
+	in.emitted = append(in.emitted, e)
+	return nil, nil
+}
@@ -0,0 +1,524 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxStringLen bounds the strings a script can build, so that a script
+// cannot exhaust the memory of the agent.
+const maxStringLen = 1 << 20
+
+var errTimeout = errors.New("script exceeded its time budget")
+
+// Map is the map value of the scripting language. The tags and fields of the
+// metric are maps whose values are checked when they are set.
+type Map struct {
+	values map[string]interface{}
+	check  func(key string, v interface{}) (interface{}, error)
+}
+
+func newMap() *Map {
+	return &Map{values: make(map[string]interface{})}
+}
+
+func (m *Map) set(key string, v interface{}) error {
+	if m.check != nil {
+		var err error
+		if v, err = m.check(key, v); err != nil {
+			return err
+		}
+	}
+	m.values[key] = v
+	return nil
+}
+
+func (m *Map) keys() []string {
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func checkTag(key string, v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case string, int64, float64, bool:
+		return toString(v), nil
+	}
+	return nil, fmt.Errorf("cannot set tag %q to %s", key, typeName(v))
+}
+
+func checkField(key string, v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case string, int64, float64, bool:
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot set field %q to %s", key, typeName(v))
+}
+
+// interp evaluates a script against a single metric.
+type interp struct {
+	vars     map[string]interface{}
+	deadline time.Time
+	steps    int
+
+	dropped bool
+	emitted []*emitted
+}
+
+type emitted struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+// returned unwinds the evaluation on a return statement.
+type returned struct{}
+
+func (in *interp) run(prog []stmt) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case returned:
+			case *Error:
+				err = r
+			default:
+				// a script must never bring down the agent
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	in.exec(prog)
+	return nil
+}
+
+// tick accounts for a step of the evaluation, and aborts it once the time
+// budget is exhausted. The clock is only read every few steps.
+func (in *interp) tick(p pos) {
+	in.steps++
+	if in.steps%256 == 0 && time.Now().After(in.deadline) {
+		panic(&Error{Pos: p, Msg: errTimeout.Error()})
+	}
+}
+
+func (in *interp) exec(list []stmt) {
+	for _, s := range list {
+		in.tick(s.position())
+
+		switch s := s.(type) {
+		case *assign:
+			in.assign(s)
+		case *exprStmt:
+			in.eval(s.x)
+		case *ifStmt:
+			if in.cond(s.cond) {
+				in.exec(s.then)
+			} else {
+				in.exec(s.els)
+			}
+		case *forStmt:
+			in.forLoop(s)
+		case *returnStmt:
+			panic(returned{})
+		}
+	}
+}
+
+func (in *interp) cond(x expr) bool {
+	v := in.eval(x)
+	b, ok := v.(bool)
+	if !ok {
+		panic(errorf(x.position(), "condition is %s, not bool", typeName(v)))
+	}
+	return b
+}
+
+func (in *interp) forLoop(s *forStmt) {
+	each := func(k, v interface{}) {
+		in.tick(s.pos)
+		in.vars[s.key] = k
+		if s.val != "" {
+			in.vars[s.val] = v
+		}
+		in.exec(s.body)
+	}
+
+	switch x := in.eval(s.x).(type) {
+	case *Map:
+		// iterate over a snapshot, the body may modify the map
+		for _, k := range x.keys() {
+			v, ok := x.values[k]
+			if !ok {
+				continue
+			}
+			each(k, v)
+		}
+	case []interface{}:
+		if s.val == "" {
+			for _, v := range x {
+				each(v, nil)
+			}
+			break
+		}
+		for i, v := range x {
+			each(int64(i), v)
+		}
+	default:
+		panic(errorf(s.x.position(), "cannot range over %s", typeName(x)))
+	}
+}
+
+func (in *interp) assign(s *assign) {
+	value := in.eval(s.value)
+	if s.op != tokAssign {
+		old := in.eval(s.target)
+		value = in.binaryOp(s.pos, compoundOp[s.op], old, value)
+	}
+
+	switch t := s.target.(type) {
+	case *ident:
+		switch t.name {
+		case "name":
+			if _, ok := value.(string); !ok {
+				panic(errorf(s.pos, "cannot set name to %s", typeName(value)))
+			}
+		case "time":
+			if _, ok := value.(int64); !ok {
+				panic(errorf(s.pos, "cannot set time to %s", typeName(value)))
+			}
+		}
+		in.vars[t.name] = value
+	case *index:
+		key := in.eval(t.key)
+		switch x := in.eval(t.x).(type) {
+		case *Map:
+			k, ok := key.(string)
+			if !ok {
+				panic(errorf(t.pos, "map key is %s, not string", typeName(key)))
+			}
+			if err := x.set(k, value); err != nil {
+				panic(errorf(s.pos, "%s", err))
+			}
+		case []interface{}:
+			i := in.listIndex(t.pos, x, key)
+			x[i] = value
+		default:
+			panic(errorf(t.pos, "cannot index %s", typeName(x)))
+		}
+	}
+}
+
+var compoundOp = map[tokenKind]tokenKind{
+	tokAddEq: tokAdd,
+	tokSubEq: tokSub,
+	tokMulEq: tokMul,
+	tokQuoEq: tokQuo,
+}
+
+func (in *interp) listIndex(p pos, l []interface{}, key interface{}) int {
+	i, ok := key.(int64)
+	if !ok {
+		panic(errorf(p, "list index is %s, not int", typeName(key)))
+	}
+	if i < 0 || i >= int64(len(l)) {
+		panic(errorf(p, "index %d out of range", i))
+	}
+	return int(i)
+}
+
+func (in *interp) eval(x expr) interface{} {
+	switch x := x.(type) {
+	case *literal:
+		return x.value
+	case *ident:
+		v, ok := in.vars[x.name]
+		if !ok {
+			panic(errorf(x.pos, "%s is not set", x.name))
+		}
+		return v
+	case *listLit:
+		l := make([]interface{}, len(x.elems))
+		for i, e := range x.elems {
+			l[i] = in.eval(e)
+		}
+		return l
+	case *mapLit:
+		m := newMap()
+		for i := range x.keys {
+			key := in.eval(x.keys[i])
+			k, ok := key.(string)
+			if !ok {
+				panic(errorf(x.keys[i].position(), "map key is %s, not string", typeName(key)))
+			}
+			m.values[k] = in.eval(x.values[i])
+		}
+		return m
+	case *unary:
+		v := in.eval(x.x)
+		switch x.op {
+		case tokNot:
+			if b, ok := v.(bool); ok {
+				return !b
+			}
+		case tokSub:
+			switch v := v.(type) {
+			case int64:
+				return -v
+			case float64:
+				return -v
+			}
+		}
+		panic(errorf(x.pos, "invalid operand %s for %s", typeName(v), x.op.String()))
+	case *binary:
+		switch x.op {
+		case tokAnd:
+			return in.cond(x.x) && in.cond(x.y)
+		case tokOr:
+			return in.cond(x.x) || in.cond(x.y)
+		}
+		return in.binaryOp(x.pos, x.op, in.eval(x.x), in.eval(x.y))
+	case *index:
+		key := in.eval(x.key)
+		switch v := in.eval(x.x).(type) {
+		case *Map:
+			k, ok := key.(string)
+			if !ok {
+				panic(errorf(x.pos, "map key is %s, not string", typeName(key)))
+			}
+			return v.values[k]
+		case []interface{}:
+			return v[in.listIndex(x.pos, v, key)]
+		case string:
+			i, ok := key.(int64)
+			if !ok || i < 0 || i >= int64(len(v)) {
+				panic(errorf(x.pos, "invalid string index %v", key))
+			}
+			return v[i : i+1]
+		default:
+			panic(errorf(x.pos, "cannot index %s", typeName(v)))
+		}
+	case *call:
+		args := make([]interface{}, len(x.args))
+		for i, a := range x.args {
+			args[i] = in.eval(a)
+		}
+		v, err := x.fn.fn(in, args)
+		if err != nil {
+			panic(errorf(x.pos, "%s: %s", x.fn.name, err))
+		}
+		return v
+	}
+	panic(errorf(x.position(), "invalid expression"))
+}
+
+func (in *interp) binaryOp(p pos, op tokenKind, x, y interface{}) interface{} {
+	switch op {
+	case tokEql:
+		return equal(x, y)
+	case tokNeq:
+		return !equal(x, y)
+	case tokIn:
+		return in.contains(p, y, x)
+	}
+
+	switch x := x.(type) {
+	case int64:
+		switch y := y.(type) {
+		case int64:
+			return intOp(p, op, x, y)
+		case float64:
+			return floatOp(p, op, float64(x), y)
+		}
+	case float64:
+		switch y := y.(type) {
+		case int64:
+			return floatOp(p, op, x, float64(y))
+		case float64:
+			return floatOp(p, op, x, y)
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return stringOp(p, op, x, y)
+		}
+	}
+	panic(errorf(p, "invalid operands %s and %s for %s", typeName(x), typeName(y), op.String()))
+}
+
+func (in *interp) contains(p pos, container, v interface{}) bool {
+	switch c := container.(type) {
+	case *Map:
+		k, ok := v.(string)
+		if !ok {
+			panic(errorf(p, "map key is %s, not string", typeName(v)))
+		}
+		_, ok = c.values[k]
+		return ok
+	case []interface{}:
+		for _, e := range c {
+			if equal(e, v) {
+				return true
+			}
+		}
+		return false
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			panic(errorf(p, "cannot look for %s in string", typeName(v)))
+		}
+		return strings.Contains(c, s)
+	}
+	panic(errorf(p, "cannot look for a value in %s", typeName(container)))
+}
+
+func intOp(p pos, op tokenKind, x, y int64) interface{} {
+	switch op {
+	case tokAdd:
+		return x + y
+	case tokSub:
+		return x - y
+	case tokMul:
+		return x * y
+	case tokQuo, tokRem:
+		if y == 0 {
+			panic(errorf(p, "integer division by zero"))
+		}
+		if op == tokQuo {
+			return x / y
+		}
+		return x % y
+	case tokLss:
+		return x < y
+	case tokLeq:
+		return x <= y
+	case tokGtr:
+		return x > y
+	case tokGeq:
+		return x >= y
+	}
+	panic(errorf(p, "invalid operator %s for int", op.String()))
+}
+
+func floatOp(p pos, op tokenKind, x, y float64) interface{} {
+	switch op {
+	case tokAdd:
+		return x + y
+	case tokSub:
+		return x - y
+	case tokMul:
+		return x * y
+	case tokQuo:
+		return x / y
+	case tokRem:
+		return math.Mod(x, y)
+	case tokLss:
+		return x < y
+	case tokLeq:
+		return x <= y
+	case tokGtr:
+		return x > y
+	case tokGeq:
+		return x >= y
+	}
+	panic(errorf(p, "invalid operator %s for float", op.String()))
+}
+
+func stringOp(p pos, op tokenKind, x, y string) interface{} {
+	switch op {
+	case tokAdd:
+		if len(x)+len(y) > maxStringLen {
+			panic(errorf(p, "string too long"))
+		}
+		return x + y
+	case tokLss:
+		return x < y
+	case tokLeq:
+		return x <= y
+	case tokGtr:
+		return x > y
+	case tokGeq:
+		return x >= y
+	}
+	panic(errorf(p, "invalid operator %s for string", op.String()))
+}
+
+// equal compares two values; numbers compare by value whatever their type.
+func equal(x, y interface{}) bool {
+	switch x := x.(type) {
+	case int64:
+		switch y := y.(type) {
+		case int64:
+			return x == y
+		case float64:
+			return float64(x) == y
+		}
+		return false
+	case float64:
+		switch y := y.(type) {
+		case int64:
+			return x == float64(y)
+		case float64:
+			return x == y
+		}
+		return false
+	case *Map:
+		y, ok := y.(*Map)
+		return ok && x == y
+	case []interface{}:
+		y, ok := y.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch y.(type) {
+	case *Map, []interface{}:
+		return false
+	}
+	return x == y
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case *Map:
+		return "map"
+	case []interface{}:
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (k tokenKind) String() string {
+	for text, kind := range operators {
+		if kind == k {
+			return fmt.Sprintf("%q", text)
+		}
+	}
+	for text, kind := range keywords {
+		if kind == k {
+			return fmt.Sprintf("%q", text)
+		}
+	}
+	return fmt.Sprintf("token(%d)", int(k))
+}
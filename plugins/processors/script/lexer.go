@@ -0,0 +1,274 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+
+	// keywords
+	tokIf
+	tokElse
+	tokFor
+	tokIn
+	tokReturn
+	tokTrue
+	tokFalse
+	tokNil
+
+	// operators and delimiters
+	tokAdd    // +
+	tokSub    // -
+	tokMul    // *
+	tokQuo    // /
+	tokRem    // %
+	tokEql    // ==
+	tokNeq    // !=
+	tokLss    // <
+	tokLeq    // <=
+	tokGtr    // >
+	tokGeq    // >=
+	tokAnd    // &&
+	tokOr     // ||
+	tokNot    // !
+	tokAssign // =
+	tokAddEq  // +=
+	tokSubEq  // -=
+	tokMulEq  // *=
+	tokQuoEq  // /=
+	tokLParen
+	tokRParen
+	tokLBrack
+	tokRBrack
+	tokLBrace
+	tokRBrace
+	tokComma
+	tokDot
+	tokColon
+	tokSemicolon
+)
+
+var keywords = map[string]tokenKind{
+	"if":     tokIf,
+	"else":   tokElse,
+	"for":    tokFor,
+	"in":     tokIn,
+	"return": tokReturn,
+	"true":   tokTrue,
+	"false":  tokFalse,
+	"nil":    tokNil,
+}
+
+var operators = map[string]tokenKind{
+	"+":  tokAdd,
+	"-":  tokSub,
+	"*":  tokMul,
+	"/":  tokQuo,
+	"%":  tokRem,
+	"==": tokEql,
+	"!=": tokNeq,
+	"<":  tokLss,
+	"<=": tokLeq,
+	">":  tokGtr,
+	">=": tokGeq,
+	"&&": tokAnd,
+	"||": tokOr,
+	"!":  tokNot,
+	"=":  tokAssign,
+	"+=": tokAddEq,
+	"-=": tokSubEq,
+	"*=": tokMulEq,
+	"/=": tokQuoEq,
+	"(":  tokLParen,
+	")":  tokRParen,
+	"[":  tokLBrack,
+	"]":  tokRBrack,
+	"{":  tokLBrace,
+	"}":  tokRBrace,
+	",":  tokComma,
+	".":  tokDot,
+	":":  tokColon,
+	";":  tokSemicolon,
+}
+
+// pos is a position in the script source.
+type pos struct {
+	line, col int
+}
+
+func (p pos) String() string {
+	return fmt.Sprintf("line %d:%d", p.line, p.col)
+}
+
+type token struct {
+	kind tokenKind
+	pos  pos
+	text string
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of script"
+	case tokNewline:
+		return "newline"
+	}
+	return strconv.Quote(t.text)
+}
+
+// Error is a syntax or runtime error of a script.
+type Error struct {
+	Pos pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func errorf(p pos, format string, args ...interface{}) *Error {
+	return &Error{Pos: p, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lex splits src into tokens. Newlines are significant as statement
+// terminators, except within parentheses and brackets.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	var depth int
+	line, col := 1, 1
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		p := pos{line, col}
+
+		switch {
+		case c == '\n':
+			if depth == 0 {
+				tokens = append(tokens, token{kind: tokNewline, pos: p, text: "\n"})
+			}
+			i++
+			line, col = line+1, 1
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			col++
+			continue
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		var tok token
+		switch {
+		case isLetter(c):
+			j := i
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			word := src[i:j]
+			kind, ok := keywords[word]
+			if !ok {
+				kind = tokIdent
+			}
+			tok = token{kind: kind, text: word}
+		case isDigit(c):
+			j := i
+			kind := tokInt
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			if j < len(src) && src[j] == '.' {
+				kind = tokFloat
+				j++
+				for j < len(src) && isDigit(src[j]) {
+					j++
+				}
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				kind = tokFloat
+				j++
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				for j < len(src) && isDigit(src[j]) {
+					j++
+				}
+			}
+			tok = token{kind: kind, text: src[i:j]}
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, errorf(p, "unterminated string")
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, errorf(p, "unterminated string")
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, errorf(p, "invalid string %s", src[i:j+1])
+			}
+			tok = token{kind: tokString, text: s}
+			// the token text is the unquoted value; advance past the quotes
+			col += j + 1 - i
+			i = j + 1
+			tok.pos = p
+			tokens = append(tokens, tok)
+			continue
+		default:
+			text := src[i : i+1]
+			if i+1 < len(src) {
+				if _, ok := operators[src[i:i+2]]; ok {
+					text = src[i : i+2]
+				}
+			}
+			kind, ok := operators[text]
+			if !ok {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, errorf(p, "unexpected character %q", r)
+			}
+			switch kind {
+			case tokLParen, tokLBrack:
+				depth++
+			case tokRParen, tokRBrack:
+				if depth > 0 {
+					depth--
+				}
+			}
+			tok = token{kind: kind, text: text}
+		}
+
+		tok.pos = p
+		tokens = append(tokens, tok)
+		i += len(tok.text)
+		col += utf8.RuneCountInString(tok.text)
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: pos{line, col}})
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,479 @@
+package script
+
+import (
+	"strconv"
+)
+
+type expr interface {
+	position() pos
+}
+
+type stmt interface {
+	position() pos
+}
+
+type (
+	literal struct {
+		pos   pos
+		value interface{}
+	}
+
+	ident struct {
+		pos  pos
+		name string
+	}
+
+	listLit struct {
+		pos   pos
+		elems []expr
+	}
+
+	mapLit struct {
+		pos    pos
+		keys   []expr
+		values []expr
+	}
+
+	unary struct {
+		pos pos
+		op  tokenKind
+		x   expr
+	}
+
+	binary struct {
+		pos  pos
+		op   tokenKind
+		x, y expr
+	}
+
+	// index is both x[key] and x.key
+	index struct {
+		pos pos
+		x   expr
+		key expr
+	}
+
+	call struct {
+		pos  pos
+		fn   *builtin
+		args []expr
+	}
+)
+
+type (
+	assign struct {
+		pos    pos
+		op     tokenKind
+		target expr
+		value  expr
+	}
+
+	exprStmt struct {
+		pos pos
+		x   expr
+	}
+
+	ifStmt struct {
+		pos  pos
+		cond expr
+		then []stmt
+		els  []stmt
+	}
+
+	forStmt struct {
+		pos      pos
+		key, val string
+		x        expr
+		body     []stmt
+	}
+
+	returnStmt struct {
+		pos pos
+	}
+)
+
+func (e *literal) position() pos    { return e.pos }
+func (e *ident) position() pos      { return e.pos }
+func (e *listLit) position() pos    { return e.pos }
+func (e *mapLit) position() pos     { return e.pos }
+func (e *unary) position() pos      { return e.pos }
+func (e *binary) position() pos     { return e.pos }
+func (e *index) position() pos      { return e.pos }
+func (e *call) position() pos       { return e.pos }
+func (s *assign) position() pos     { return s.pos }
+func (s *exprStmt) position() pos   { return s.pos }
+func (s *ifStmt) position() pos     { return s.pos }
+func (s *forStmt) position() pos    { return s.pos }
+func (s *returnStmt) position() pos { return s.pos }
+
+// binary operator precedences, higher binds tighter
+var precedence = map[tokenKind]int{
+	tokOr:  1,
+	tokAnd: 2,
+	tokEql: 3,
+	tokNeq: 3,
+	tokLss: 3,
+	tokLeq: 3,
+	tokGtr: 3,
+	tokGeq: 3,
+	tokIn:  3,
+	tokAdd: 4,
+	tokSub: 4,
+	tokMul: 5,
+	tokQuo: 5,
+	tokRem: 5,
+}
+
+// globals are the variables describing the metric, and the state shared
+// between runs of the script.
+var globals = map[string]bool{
+	"name":   true,
+	"tags":   true,
+	"fields": true,
+	"time":   true,
+	"state":  true,
+}
+
+type parser struct {
+	tokens []token
+	tok    token
+	next   int
+
+	// variables assigned so far; reading a variable that is never assigned
+	// before its use is a compile error.
+	defined map[string]bool
+}
+
+// parse compiles the source of a script.
+func parse(src string) (prog []stmt, err error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, defined: make(map[string]bool)}
+	p.advance()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			prog, err = nil, e
+		}
+	}()
+
+	prog = p.stmts(tokEOF)
+	return prog, nil
+}
+
+func (p *parser) advance() {
+	p.tok = p.tokens[p.next]
+	if p.next < len(p.tokens)-1 {
+		p.next++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	panic(errorf(p.tok.pos, format, args...))
+}
+
+func (p *parser) expect(kind tokenKind, what string) token {
+	tok := p.tok
+	if tok.kind != kind {
+		p.errorf("expected %s, found %s", what, tok)
+	}
+	p.advance()
+	return tok
+}
+
+func (p *parser) skipNewlines() {
+	for p.tok.kind == tokNewline {
+		p.advance()
+	}
+}
+
+// stmts parses statements up to the end token, which is not consumed.
+func (p *parser) stmts(end tokenKind) []stmt {
+	var list []stmt
+	for {
+		for p.tok.kind == tokNewline || p.tok.kind == tokSemicolon {
+			p.advance()
+		}
+		if p.tok.kind == end {
+			return list
+		}
+		if p.tok.kind == tokEOF {
+			p.errorf(`expected "}", found %s`, p.tok)
+		}
+		list = append(list, p.stmt())
+
+		switch p.tok.kind {
+		case tokNewline, tokSemicolon, end:
+		default:
+			p.errorf("unexpected %s at end of statement", p.tok)
+		}
+	}
+}
+
+func (p *parser) block() []stmt {
+	p.expect(tokLBrace, `"{"`)
+	list := p.stmts(tokRBrace)
+	p.expect(tokRBrace, `"}"`)
+	return list
+}
+
+func (p *parser) stmt() stmt {
+	switch p.tok.kind {
+	case tokIf:
+		return p.ifStmt()
+	case tokFor:
+		return p.forStmt()
+	case tokReturn:
+		s := &returnStmt{pos: p.tok.pos}
+		p.advance()
+		return s
+	}
+
+	start := p.tok.pos
+	var x expr
+	if p.tok.kind == tokIdent && isAssignOp(p.tokens[p.next].kind) {
+		// the variable may be defined by this assignment
+		x = &ident{pos: start, name: p.tok.text}
+		p.advance()
+	} else {
+		x = p.expr(1)
+	}
+	if op := p.tok.kind; isAssignOp(op) {
+		p.advance()
+		p.checkTarget(x, op)
+		value := p.expr(1)
+		if id, ok := x.(*ident); ok {
+			p.defined[id.name] = true
+		}
+		return &assign{pos: start, op: op, target: x, value: value}
+	}
+
+	if _, ok := x.(*call); !ok {
+		panic(errorf(start, "expression is not used"))
+	}
+	return &exprStmt{pos: start, x: x}
+}
+
+func isAssignOp(kind tokenKind) bool {
+	switch kind {
+	case tokAssign, tokAddEq, tokSubEq, tokMulEq, tokQuoEq:
+		return true
+	}
+	return false
+}
+
+// checkTarget verifies that x can be assigned to.
+func (p *parser) checkTarget(x expr, op tokenKind) {
+	switch x := x.(type) {
+	case *ident:
+		switch x.name {
+		case "tags", "fields", "state":
+			panic(errorf(x.pos, "cannot assign to %s", x.name))
+		}
+		if op != tokAssign && !p.defined[x.name] && !globals[x.name] {
+			panic(errorf(x.pos, "undefined: %s", x.name))
+		}
+	case *index:
+	default:
+		panic(errorf(x.position(), "cannot assign to expression"))
+	}
+}
+
+func (p *parser) ifStmt() stmt {
+	s := &ifStmt{pos: p.tok.pos}
+	p.advance()
+	s.cond = p.expr(1)
+	s.then = p.block()
+	if p.tok.kind == tokElse {
+		p.advance()
+		if p.tok.kind == tokIf {
+			s.els = []stmt{p.ifStmt()}
+		} else {
+			s.els = p.block()
+		}
+	}
+	return s
+}
+
+func (p *parser) forStmt() stmt {
+	s := &forStmt{pos: p.tok.pos}
+	p.advance()
+	s.key = p.expect(tokIdent, "variable name").text
+	if p.tok.kind == tokComma {
+		p.advance()
+		s.val = p.expect(tokIdent, "variable name").text
+	}
+	for _, name := range []string{s.key, s.val} {
+		if globals[name] {
+			p.errorf("cannot assign to %s", name)
+		}
+		if name != "" {
+			p.defined[name] = true
+		}
+	}
+	p.expect(tokIn, `"in"`)
+	s.x = p.expr(1)
+	s.body = p.block()
+	return s
+}
+
+// expr parses a binary expression whose operators have at least the given
+// precedence.
+func (p *parser) expr(prec int) expr {
+	x := p.unary()
+	for {
+		op := p.tok.kind
+		opPrec, ok := precedence[op]
+		if !ok || opPrec < prec {
+			return x
+		}
+		opPos := p.tok.pos
+		p.advance()
+		p.skipNewlines()
+		y := p.expr(opPrec + 1)
+		x = &binary{pos: opPos, op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() expr {
+	switch p.tok.kind {
+	case tokSub, tokNot:
+		e := &unary{pos: p.tok.pos, op: p.tok.kind}
+		p.advance()
+		e.x = p.unary()
+		return e
+	}
+	return p.postfix(p.operand())
+}
+
+func (p *parser) postfix(x expr) expr {
+	for {
+		switch p.tok.kind {
+		case tokDot:
+			p.advance()
+			key := p.expect(tokIdent, "name after \".\"")
+			x = &index{pos: key.pos, x: x, key: &literal{pos: key.pos, value: key.text}}
+		case tokLBrack:
+			start := p.tok.pos
+			p.advance()
+			key := p.expr(1)
+			p.expect(tokRBrack, `"]"`)
+			x = &index{pos: start, x: x, key: key}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) operand() expr {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		p.advance()
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			panic(errorf(tok.pos, "invalid integer %s", tok.text))
+		}
+		return &literal{pos: tok.pos, value: v}
+	case tokFloat:
+		p.advance()
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			panic(errorf(tok.pos, "invalid float %s", tok.text))
+		}
+		return &literal{pos: tok.pos, value: v}
+	case tokString:
+		p.advance()
+		return &literal{pos: tok.pos, value: tok.text}
+	case tokTrue, tokFalse:
+		p.advance()
+		return &literal{pos: tok.pos, value: tok.kind == tokTrue}
+	case tokNil:
+		p.advance()
+		return &literal{pos: tok.pos, value: nil}
+	case tokIdent:
+		p.advance()
+		if p.tok.kind == tokLParen {
+			return p.call(tok)
+		}
+		if !globals[tok.text] && !p.defined[tok.text] {
+			panic(errorf(tok.pos, "undefined: %s", tok.text))
+		}
+		return &ident{pos: tok.pos, name: tok.text}
+	case tokLParen:
+		p.advance()
+		x := p.expr(1)
+		p.expect(tokRParen, `")"`)
+		return x
+	case tokLBrack:
+		return p.listLit()
+	case tokLBrace:
+		return p.mapLit()
+	}
+	p.errorf("unexpected %s", tok)
+	return nil
+}
+
+func (p *parser) call(name token) expr {
+	fn, ok := builtins[name.text]
+	if !ok {
+		panic(errorf(name.pos, "undefined function: %s", name.text))
+	}
+
+	c := &call{pos: name.pos, fn: fn}
+	p.expect(tokLParen, `"("`)
+	for p.tok.kind != tokRParen {
+		c.args = append(c.args, p.expr(1))
+		if p.tok.kind != tokComma {
+			break
+		}
+		p.advance()
+	}
+	p.expect(tokRParen, `")"`)
+
+	if len(c.args) < fn.minArgs || (fn.maxArgs >= 0 && len(c.args) > fn.maxArgs) {
+		panic(errorf(name.pos, "wrong number of arguments for %s: %d", fn.name, len(c.args)))
+	}
+	return c
+}
+
+func (p *parser) listLit() expr {
+	l := &listLit{pos: p.tok.pos}
+	p.advance()
+	for p.tok.kind != tokRBrack {
+		l.elems = append(l.elems, p.expr(1))
+		if p.tok.kind != tokComma {
+			break
+		}
+		p.advance()
+	}
+	p.expect(tokRBrack, `"]"`)
+	return l
+}
+
+func (p *parser) mapLit() expr {
+	m := &mapLit{pos: p.tok.pos}
+	p.advance()
+	p.skipNewlines()
+	for p.tok.kind != tokRBrace {
+		m.keys = append(m.keys, p.expr(1))
+		p.expect(tokColon, `":"`)
+		p.skipNewlines()
+		m.values = append(m.values, p.expr(1))
+		p.skipNewlines()
+		if p.tok.kind != tokComma {
+			break
+		}
+		p.advance()
+		p.skipNewlines()
+	}
+	p.expect(tokRBrace, `"}"`)
+	return m
+}
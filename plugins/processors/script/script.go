@@ -0,0 +1,196 @@
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## The script evaluated for each metric, either inline or from a file.
+  ## The metric is available as "name", "tags", "fields" and "time".
+  source = '''
+    if "used" in fields && "total" in fields {
+      fields.used_percent = float(fields.used) / float(fields.total) * 100.0
+    }
+  '''
+  # script = "/etc/telegraf/script.tgs"
+
+  ## Maximum time the script may run for each metric; a metric whose script
+  ## does not complete in time is passed on unchanged.
+  # timeout = "10ms"
+`
+
+const defaultTimeout = 10 * time.Millisecond
+
+type Script struct {
+	Source  string            `toml:"source"`
+	Script  string            `toml:"script"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	prog []stmt
+	// state is shared between all the runs of the script.
+	state *Map
+}
+
+func NewScript() *Script {
+	return &Script{
+		Timeout: internal.Duration{Duration: defaultTimeout},
+		state:   newMap(),
+	}
+}
+
+func (s *Script) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Script) Description() string {
+	return "Compute, modify or drop metrics with a script."
+}
+
+// Init compiles the script, so that syntax errors are reported when the
+// configuration is loaded.
+func (s *Script) Init() error {
+	src := s.Source
+	switch {
+	case s.Source != "" && s.Script != "":
+		return fmt.Errorf("only one of source and script can be set")
+	case s.Script != "":
+		b, err := ioutil.ReadFile(s.Script)
+		if err != nil {
+			return err
+		}
+		src = string(b)
+	case s.Source == "":
+		return fmt.Errorf("either source or script must be set")
+	}
+
+	prog, err := parse(src)
+	if err != nil {
+		return fmt.Errorf("invalid script: %s", err)
+	}
+	s.prog = prog
+	if s.state == nil {
+		s.state = newMap()
+	}
+	return nil
+}
+
+func (s *Script) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if s.prog == nil {
+		if err := s.Init(); err != nil {
+			log.Printf("E! [processors.script] %s", err)
+			return in
+		}
+	}
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		out = append(out, s.run(m)...)
+	}
+	return out
+}
+
+// run evaluates the script for m and returns the resulting metrics. If the
+// script fails, m is returned unchanged.
+func (s *Script) run(m telegraf.Metric) []telegraf.Metric {
+	tags := &Map{values: make(map[string]interface{}), check: checkTag}
+	for k, v := range m.Tags() {
+		tags.values[k] = v
+	}
+	fields := &Map{values: m.Fields(), check: checkField}
+
+	name, t := m.Name(), m.Time().UnixNano()
+	in := &interp{
+		vars: map[string]interface{}{
+			"name":   name,
+			"tags":   tags,
+			"fields": fields,
+			"time":   t,
+			"state":  s.state,
+		},
+		deadline: time.Now().Add(s.Timeout.Duration),
+	}
+	if err := in.run(s.prog); err != nil {
+		log.Printf("E! [processors.script] error running script on %s: %s",
+			m.Name(), err)
+		return []telegraf.Metric{m}
+	}
+
+	var out []telegraf.Metric
+	if !in.dropped {
+		out = append(out, s.result(m, in))
+	}
+	for _, e := range in.emitted {
+		em, err := metric.New(e.name, e.tags, e.fields, e.time, m.Type())
+		if err != nil {
+			log.Printf("E! [processors.script] could not emit metric %s: %s",
+				e.name, err)
+			continue
+		}
+		out = append(out, em)
+	}
+	return out
+}
+
+// result builds the metric modified by the script, m itself is returned if
+// it was not modified.
+func (s *Script) result(m telegraf.Metric, in *interp) telegraf.Metric {
+	name := in.vars["name"].(string)
+	t := in.vars["time"].(int64)
+	tags := make(map[string]string, len(in.vars["tags"].(*Map).values))
+	for k, v := range in.vars["tags"].(*Map).values {
+		tags[k] = v.(string)
+	}
+	fields := in.vars["fields"].(*Map).values
+
+	if name == m.Name() && t == m.Time().UnixNano() &&
+		equalTags(tags, m.Tags()) && equalFields(fields, m.Fields()) {
+		return m
+	}
+
+	result, err := metric.New(name, tags, fields, time.Unix(0, t), m.Type())
+	if err != nil {
+		log.Printf("E! [processors.script] could not modify metric %s: %s",
+			m.Name(), err)
+		return m
+	}
+	result.SetAggregate(m.IsAggregate())
+	return metric.WithTrackingFrom(m, result)
+}
+
+func equalTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFields(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	processors.Add("script", func() telegraf.Processor {
+		return NewScript()
+	})
+}
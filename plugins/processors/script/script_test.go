@@ -0,0 +1,270 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New(name, tags, fields, time.Unix(1500000000, 0))
+	return m
+}
+
+func newScript(t *testing.T, src string) *Script {
+	s := NewScript()
+	s.Source = src
+	require.NoError(t, s.Init())
+	return s
+}
+
+func TestDerivedField(t *testing.T) {
+	s := newScript(t, `
+# compute the ratio of used memory
+if "used" in fields && "total" in fields {
+  fields.used_percent = float(fields.used) / float(fields.total) * 100.0
+}
+`)
+	m := newMetric("mem", nil, map[string]interface{}{
+		"used":  int64(25),
+		"total": int64(100),
+	})
+
+	out := s.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{
+		"used":         int64(25),
+		"total":        int64(100),
+		"used_percent": 25.0,
+	}, out[0].Fields())
+	assert.Equal(t, m.Time(), out[0].Time())
+}
+
+func TestTagsAndName(t *testing.T) {
+	s := newScript(t, `
+name = "disk_" + name
+if tags.env == "prod" {
+  tags.tier = 1
+} else {
+  tags.tier = 2
+}
+delete(tags, "env")
+fields.free_mb = fields.free / 1024 / 1024
+delete(fields, "free")
+time += 1000000000
+`)
+	m := newMetric("usage",
+		map[string]string{"env": "prod", "path": "/"},
+		map[string]interface{}{"free": int64(10 * 1024 * 1024)},
+	)
+
+	out := s.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, "disk_usage", out[0].Name())
+	assert.Equal(t, map[string]string{"tier": "1", "path": "/"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"free_mb": int64(10)}, out[0].Fields())
+	assert.Equal(t, m.Time().Add(time.Second), out[0].Time())
+}
+
+func TestUnchangedMetric(t *testing.T) {
+	s := newScript(t, `
+if fields.value > 100 {
+  fields.value = 100
+}
+`)
+	m := newMetric("cpu", nil, map[string]interface{}{"value": 42.0})
+
+	out := s.Apply(m)
+	require.Len(t, out, 1)
+	assert.True(t, m == out[0])
+}
+
+func TestDropAndEmit(t *testing.T) {
+	s := newScript(t, `
+for k, v in fields {
+  emit(name + "_" + k, tags, {"value": v})
+}
+drop()
+`)
+	m := newMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"idle": 90.0, "user": 10.0},
+	)
+
+	out := s.Apply(m)
+	require.Len(t, out, 2)
+	// fields are iterated in sorted order
+	assert.Equal(t, "cpu_idle", out[0].Name())
+	assert.Equal(t, map[string]string{"host": "localhost"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": 90.0}, out[0].Fields())
+	assert.Equal(t, m.Time(), out[0].Time())
+	assert.Equal(t, "cpu_user", out[1].Name())
+}
+
+func TestState(t *testing.T) {
+	s := newScript(t, `
+key = name + "," + tags.host
+if key in state {
+  fields.delta = fields.value - state[key]
+}
+state[key] = fields.value
+`)
+	m1 := newMetric("net", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(10)})
+	m2 := newMetric("net", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(15)})
+
+	out := s.Apply(m1, m2)
+	require.Len(t, out, 2)
+	assert.Equal(t, map[string]interface{}{"value": int64(10)}, out[0].Fields())
+	assert.Equal(t, map[string]interface{}{"value": int64(15), "delta": int64(5)}, out[1].Fields())
+}
+
+func TestRuntimeErrorKeepsMetric(t *testing.T) {
+	s := newScript(t, `
+fields.used = "x"
+fields.ratio = fields.used / fields.total
+`)
+	m := newMetric("mem", nil, map[string]interface{}{"used": int64(1), "total": int64(2)})
+
+	out := s.Apply(m)
+	require.Len(t, out, 1)
+	assert.True(t, m == out[0])
+}
+
+func TestTimeout(t *testing.T) {
+	s := NewScript()
+	s.Source = `
+l = split("` + strings.Repeat(",", 1000) + `", ",")
+n = 0
+for a in l {
+  for b in l {
+    for c in l {
+      n += 1
+    }
+  }
+}
+fields.n = n
+`
+	s.Timeout = internal.Duration{Duration: time.Millisecond}
+	require.NoError(t, s.Init())
+	m := newMetric("cpu", nil, map[string]interface{}{"value": 42.0})
+
+	start := time.Now()
+	out := s.Apply(m)
+	assert.True(t, time.Since(start) < time.Second)
+	require.Len(t, out, 1)
+	assert.True(t, m == out[0])
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"fields.x = ", "line 1:12: unexpected end of script"},
+		{"if x > 1 {\n}", "line 1:4: undefined: x"},
+		{"fields.x = foo(1)", "line 1:12: undefined function: foo"},
+		{"fields = {}", "line 1:1: cannot assign to fields"},
+		{"fields.x = len(1, 2)", "line 1:12: wrong number of arguments for len: 2"},
+		{"fields.x = \"abc", "line 1:12: unterminated string"},
+		{"fields.x == 1", "line 1:1: expression is not used"},
+		{"if true {\n  fields.x = 1\n", "line 3:1: expected \"}\", found end of script"},
+		{"fields.x = 1 2", "line 1:14: unexpected \"2\" at end of statement"},
+		{"fields.x = 1 @ 2", "line 1:14: unexpected character '@'"},
+	}
+
+	for _, tt := range tests {
+		s := NewScript()
+		s.Source = tt.src
+		err := s.Init()
+		if assert.Error(t, err, tt.src) {
+			assert.Equal(t, "invalid script: "+tt.err, err.Error(), tt.src)
+		}
+	}
+}
+
+func TestSourceOrScript(t *testing.T) {
+	s := NewScript()
+	assert.Error(t, s.Init())
+
+	s.Source = "drop()"
+	s.Script = "/tmp/script.tgs"
+	assert.Error(t, s.Init())
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected interface{}
+	}{
+		{`7 / 2`, int64(3)},
+		{`7 % 2`, int64(1)},
+		{`7 / 2.0`, 3.5},
+		{`1 + 2 * 3 - -1`, int64(8)},
+		{`(1 + 2) * 3`, int64(9)},
+		{`1 == 1.0`, true},
+		{`"a" < "b" && !(1 > 2)`, true},
+		{`false || 1 != 2`, true},
+		{`"b" in ["a", "b"]`, true},
+		{`"lo" in "hello"`, true},
+		{`"x" in {"x": 1}`, true},
+		{`{"x": 1}["x"] + [1, 2][1]`, int64(3)},
+		{`"abc"[1]`, "b"},
+		{`len("abc") + len([1]) + len({})`, int64(4)},
+		{`int("42") + int(4.9) + int(true)`, int64(47)},
+		{`float("4.5") + float(1)`, 5.5},
+		{`string(4.5) + string(1) + string(false)`, "4.51false"},
+		{`bool("true") && bool(1)`, true},
+		{`upper("a") + lower("B") + trim(" c ")`, "Abc"},
+		{`startswith("abc", "ab") && endswith("abc", "bc")`, true},
+		{`replace("a-b-c", "-", "_")`, "a_b_c"},
+		{`join(split("a,b", ","), ";")`, "a;b"},
+		{`join(keys({"b": 1, "a": 2}), "")`, "ab"},
+		{`round(2.5) + floor(1.9) + ceil(0.1) + abs(-1)`, 6.0},
+		{`sqrt(16) + pow(2, 3)`, 12.0},
+		{`min(3, 1.5, 2) + max([1, 5, 2])`, 6.5},
+		{`type(nil) + type({}) + type([])`, "nilmaplist"},
+		{`fields.missing == nil`, true},
+	}
+
+	for _, tt := range tests {
+		s := newScript(t, "fields.result = string("+tt.src+")\nfields.type = type("+tt.src+")")
+		m := newMetric("test", nil, map[string]interface{}{"value": int64(1)})
+
+		out := s.Apply(m)
+		require.Len(t, out, 1, tt.src)
+		assert.Equal(t, toString(tt.expected), out[0].Fields()["result"], tt.src)
+		assert.Equal(t, typeName(tt.expected), out[0].Fields()["type"], tt.src)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	s := newScript(t, `
+total = 0
+for v in [1, 2, 3] {
+  total += v
+}
+for i, v in ["a", "b"] {
+  total += i
+}
+for k in tags {
+  if k == "skip" {
+    return
+  }
+}
+fields.total = total
+`)
+	out := s.Apply(
+		newMetric("a", nil, map[string]interface{}{"value": int64(1)}),
+		newMetric("b", map[string]string{"skip": "true"}, map[string]interface{}{"value": int64(1)}),
+	)
+	require.Len(t, out, 2)
+	assert.Equal(t, int64(7), out[0].Fields()["total"])
+	assert.NotContains(t, out[1].Fields(), "total")
+}
@@ -0,0 +1,66 @@
+package carbon2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// sanitizedChars replaces the characters separating the tags and their keys
+// and values.
+var sanitizedChars = strings.NewReplacer(" ", "_", "=", "_")
+
+// Carbon2Serializer writes metrics in the Carbon2 format, a line per field:
+//
+//	metric=cpu field=usage_idle cpu=cpu0 host=server01  91.5 1458229140
+//
+// The measurement, field and tags are intrinsic tags, which are followed by
+// two spaces, the value and the timestamp in seconds.
+type Carbon2Serializer struct {
+}
+
+func (s *Carbon2Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	out := []byte{}
+	timestamp := metric.UnixNano() / 1000000000
+
+	var tags string
+	metricTags := metric.Tags()
+	keys := make([]string, 0, len(metricTags))
+	for k := range metricTags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags += " " + sanitizedChars.Replace(k) + "=" + sanitizedChars.Replace(metricTags[k])
+	}
+
+	fields := metric.Fields()
+	fieldNames := make([]string, 0, len(fields))
+	for fn := range fields {
+		fieldNames = append(fieldNames, fn)
+	}
+	sort.Strings(fieldNames)
+
+	for _, fn := range fieldNames {
+		value := fields[fn]
+		switch v := value.(type) {
+		case string:
+			continue
+		case bool:
+			if v {
+				value = 1
+			} else {
+				value = 0
+			}
+		}
+		out = append(out, fmt.Sprintf("metric=%s field=%s%s  %v %d\n",
+			sanitizedChars.Replace(metric.Name()),
+			sanitizedChars.Replace(fn),
+			tags,
+			value,
+			timestamp)...)
+	}
+	return out, nil
+}
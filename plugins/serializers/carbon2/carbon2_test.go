@@ -0,0 +1,52 @@
+package carbon2
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"host": "localhost",
+		"cpu":  "cpu 0",
+	}
+	fields := map[string]interface{}{
+		"usage_idle": float64(91.5),
+		"usage_busy": int64(8),
+		"active":     true,
+		"state":      "ok",
+	}
+	m, err := metric.New("cpu", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := Carbon2Serializer{}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expected := fmt.Sprintf(
+		"metric=cpu field=active cpu=cpu_0 host=localhost  1 %d\n"+
+			"metric=cpu field=usage_busy cpu=cpu_0 host=localhost  8 %d\n"+
+			"metric=cpu field=usage_idle cpu=cpu_0 host=localhost  91.5 %d\n",
+		now.Unix(), now.Unix(), now.Unix())
+	assert.Equal(t, expected, string(buf))
+}
+
+func TestSerializeMetricNoTags(t *testing.T) {
+	now := time.Now()
+	m, err := metric.New("mem", map[string]string{},
+		map[string]interface{}{"used=percent": float64(12.5)}, now)
+	assert.NoError(t, err)
+
+	s := Carbon2Serializer{}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		fmt.Sprintf("metric=mem field=used_percent  12.5 %d\n", now.Unix()),
+		string(buf))
+}
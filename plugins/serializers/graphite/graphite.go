@@ -2,6 +2,7 @@ package graphite
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -13,14 +14,24 @@ const DEFAULT_TEMPLATE = "host.tags.measurement.field"
 var (
 	fieldDeleter   = strings.NewReplacer(".FIELDNAME", "", "FIELDNAME.", "")
 	sanitizedChars = strings.NewReplacer("/", "-", "@", "-", "*", "-", " ", "_", "..", ".", `\`, "", ")", "_", "(", "_")
+	// taggedInvalidChars are the characters Graphite does not accept in the
+	// names and values of tagged series.
+	taggedInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9\-:._\p{L}]`)
 )
 
 type GraphiteSerializer struct {
 	Prefix   string
 	Template string
+	// TagSupport writes tagged series, ie "cpu.usage_idle;host=server01",
+	// instead of putting the tags in the path with the Template.
+	TagSupport bool
 }
 
 func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.TagSupport {
+		return s.serializeTagged(metric), nil
+	}
+
 	out := []byte{}
 
 	// Convert UnixNano to Unix timestamps
@@ -53,6 +64,56 @@ func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	return out, nil
 }
 
+// serializeTagged writes a line per field, with the path made of the prefix,
+// measurement and field, followed by the tags sorted by key.
+func (s *GraphiteSerializer) serializeTagged(metric telegraf.Metric) []byte {
+	out := []byte{}
+	timestamp := metric.UnixNano() / 1000000000
+
+	var tags string
+	metricTags := metric.Tags()
+	keys := make([]string, 0, len(metricTags))
+	for k := range metricTags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := taggedInvalidChars.ReplaceAllString(metricTags[k], "_")
+		if v == "" {
+			// Graphite rejects tags without a value
+			continue
+		}
+		tags += ";" + taggedInvalidChars.ReplaceAllString(k, "_") + "=" + v
+	}
+
+	for fieldName, value := range metric.Fields() {
+		switch v := value.(type) {
+		case string:
+			continue
+		case bool:
+			if v {
+				value = 1
+			} else {
+				value = 0
+			}
+		}
+
+		path := metric.Name()
+		if fieldName != "value" {
+			path += "." + fieldName
+		}
+		if s.Prefix != "" {
+			path = s.Prefix + "." + path
+		}
+		out = append(out, fmt.Sprintf("%s%s %#v %d\n",
+			taggedInvalidChars.ReplaceAllString(path, "_"),
+			tags,
+			value,
+			timestamp)...)
+	}
+	return out
+}
+
 // SerializeBucketName will take the given measurement name and tags and
 // produce a graphite bucket. It will use the GraphiteSerializer.Template
 // to generate this, or DEFAULT_TEMPLATE.
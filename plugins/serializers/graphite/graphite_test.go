@@ -353,6 +353,37 @@ func TestSerializeMetricPrefix(t *testing.T) {
 	assert.Equal(t, expS, mS)
 }
 
+func TestSerializeTagSupport(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"host":       "localhost",
+		"cpu":        "cpu 0",
+		"datacenter": "us-west-2",
+		"empty":      "",
+	}
+	fields := map[string]interface{}{
+		"usage_idle": float64(91.5),
+		"value":      int64(3),
+		"active":     true,
+		"state":      "ok",
+	}
+	m, err := metric.New("cpu", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := GraphiteSerializer{Prefix: "prefix", TagSupport: true}
+	buf, _ := s.Serialize(m)
+	mS := strings.Split(strings.TrimSpace(string(buf)), "\n")
+
+	expS := []string{
+		fmt.Sprintf("prefix.cpu.usage_idle;cpu=cpu_0;datacenter=us-west-2;host=localhost 91.5 %d", now.Unix()),
+		fmt.Sprintf("prefix.cpu;cpu=cpu_0;datacenter=us-west-2;host=localhost 3 %d", now.Unix()),
+		fmt.Sprintf("prefix.cpu.active;cpu=cpu_0;datacenter=us-west-2;host=localhost 1 %d", now.Unix()),
+	}
+	sort.Strings(mS)
+	sort.Strings(expS)
+	assert.Equal(t, expS, mS)
+}
+
 func TestSerializeBucketNameNoHost(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
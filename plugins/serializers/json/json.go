@@ -2,6 +2,9 @@ package json
 
 import (
 	ejson "encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -9,21 +12,117 @@ import (
 
 type JsonSerializer struct {
 	TimestampUnits time.Duration
+
+	// TimestampFormat is either "unix", the default, for a number of
+	// TimestampUnits since the epoch, or "rfc3339" for an RFC3339 string in
+	// UTC.
+	TimestampFormat string
+	// NameKey and TimestampKey are the keys of the metric name and
+	// timestamp, "name" and "timestamp" when empty.
+	NameKey      string
+	TimestampKey string
+	// Flatten writes the tags and fields at the top level of the object,
+	// with their keys prefixed by TagPrefix and FieldPrefix, instead of in
+	// "tags" and "fields" objects.
+	Flatten     bool
+	TagPrefix   string
+	FieldPrefix string
+	// PerField writes an object per field, with the field key and value in
+	// "field" and "value", instead of an object per metric.
+	PerField bool
+	// Batch writes the batches of metrics as a single JSON array, instead of
+	// an object per line.
+	Batch bool
+}
+
+// Validate checks the timestamp format, and that the keys of the objects can
+// not collide: the keys must not be used twice, including the "tags" and
+// "fields" objects when not flattening, and the prefixes of flattened tags
+// and fields must be set and distinct, and the other keys must not start
+// with them.
+func (s *JsonSerializer) Validate() error {
+	switch s.TimestampFormat {
+	case "", "unix", "rfc3339":
+	default:
+		return fmt.Errorf("invalid json_timestamp_format %q, must be unix or rfc3339",
+			s.TimestampFormat)
+	}
+
+	keys := []string{s.nameKey(), s.timestampKey()}
+	if s.PerField {
+		keys = append(keys, "field", "value")
+	}
+	if !s.Flatten {
+		keys = append(keys, "tags")
+		if !s.PerField {
+			keys = append(keys, "fields")
+		}
+	}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if seen[k] {
+			return fmt.Errorf("json key %q is used twice", k)
+		}
+		seen[k] = true
+	}
+
+	if !s.Flatten {
+		return nil
+	}
+	if s.TagPrefix == "" || s.FieldPrefix == "" {
+		return fmt.Errorf("json_tag_prefix and json_field_prefix must be set with json_flatten")
+	}
+	if strings.HasPrefix(s.TagPrefix, s.FieldPrefix) || strings.HasPrefix(s.FieldPrefix, s.TagPrefix) {
+		return fmt.Errorf("json_tag_prefix %q and json_field_prefix %q must not start with one another",
+			s.TagPrefix, s.FieldPrefix)
+	}
+	for _, k := range keys {
+		if strings.HasPrefix(k, s.TagPrefix) || strings.HasPrefix(k, s.FieldPrefix) {
+			return fmt.Errorf("json key %q must not start with json_tag_prefix or json_field_prefix", k)
+		}
+	}
+	return nil
 }
 
 func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
-	m := make(map[string]interface{})
-	units_nanoseconds := s.TimestampUnits.Nanoseconds()
-	// if the units passed in were less than or equal to zero,
-	// then serialize the timestamp in seconds (the default)
-	if units_nanoseconds <= 0 {
-		units_nanoseconds = 1000000000
-	}
-	m["tags"] = metric.Tags()
-	m["fields"] = metric.Fields()
-	m["name"] = metric.Name()
-	m["timestamp"] = metric.UnixNano() / units_nanoseconds
-	serialized, err := ejson.Marshal(m)
+	var out []byte
+	for _, obj := range s.createObjects(metric) {
+		serialized, err := ejson.Marshal(obj)
+		if err != nil {
+			return []byte{}, err
+		}
+		out = append(out, serialized...)
+		out = append(out, '\n')
+	}
+
+	return out, nil
+}
+
+// Batching returns whether the batches are written as JSON arrays.
+func (s *JsonSerializer) Batching() bool {
+	return s.Batch
+}
+
+// SerializeBatch writes metrics as a single JSON array of metric objects
+// when Batch is set, and as an object per line otherwise.
+func (s *JsonSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if !s.Batch {
+		var out []byte
+		for _, metric := range metrics {
+			serialized, err := s.Serialize(metric)
+			if err != nil {
+				return []byte{}, err
+			}
+			out = append(out, serialized...)
+		}
+		return out, nil
+	}
+
+	objects := make([]map[string]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		objects = append(objects, s.createObjects(metric)...)
+	}
+	serialized, err := ejson.Marshal(objects)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -31,3 +130,74 @@ func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 
 	return serialized, nil
 }
+
+// createObjects returns the object of metric, or its objects when there is
+// one per field.
+func (s *JsonSerializer) createObjects(metric telegraf.Metric) []map[string]interface{} {
+	if !s.PerField {
+		return []map[string]interface{}{s.createObject(metric, metric.Fields())}
+	}
+
+	fields := metric.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	objects := make([]map[string]interface{}, 0, len(fields))
+	for _, k := range keys {
+		obj := s.createObject(metric, nil)
+		obj["field"] = k
+		obj["value"] = fields[k]
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+func (s *JsonSerializer) createObject(metric telegraf.Metric, fields map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	if s.Flatten {
+		for k, v := range metric.Tags() {
+			m[s.TagPrefix+k] = v
+		}
+		for k, v := range fields {
+			m[s.FieldPrefix+k] = v
+		}
+	} else {
+		m["tags"] = metric.Tags()
+		if fields != nil {
+			m["fields"] = fields
+		}
+	}
+
+	m[s.nameKey()] = metric.Name()
+
+	timestampKey := s.timestampKey()
+	if s.TimestampFormat == "rfc3339" {
+		m[timestampKey] = metric.Time().UTC().Format(time.RFC3339Nano)
+	} else {
+		units_nanoseconds := s.TimestampUnits.Nanoseconds()
+		// if the units passed in were less than or equal to zero,
+		// then serialize the timestamp in seconds (the default)
+		if units_nanoseconds <= 0 {
+			units_nanoseconds = 1000000000
+		}
+		m[timestampKey] = metric.UnixNano() / units_nanoseconds
+	}
+	return m
+}
+
+func (s *JsonSerializer) nameKey() string {
+	if s.NameKey == "" {
+		return "name"
+	}
+	return s.NameKey
+}
+
+func (s *JsonSerializer) timestampKey() string {
+	if s.TimestampKey == "" {
+		return "timestamp"
+	}
+	return s.TimestampKey
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 )
 
@@ -108,3 +109,140 @@ func TestSerializeMetricWithEscapes(t *testing.T) {
 	expS := []byte(fmt.Sprintf(`{"fields":{"U,age=Idle":90},"name":"My CPU","tags":{"cpu tag":"cpu0"},"timestamp":%d}`, now.Unix()) + "\n")
 	assert.Equal(t, string(expS), string(buf))
 }
+
+func TestSerializeBatch(t *testing.T) {
+	now := time.Now()
+	m1, err := metric.New("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5)}, now)
+	assert.NoError(t, err)
+	m2, err := metric.New("mem", map[string]string{},
+		map[string]interface{}{"used": int64(10)}, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{Batch: true}
+	assert.True(t, s.Batching())
+	buf, err := s.SerializeBatch([]telegraf.Metric{m1, m2})
+	assert.NoError(t, err)
+	expS := fmt.Sprintf(`[{"fields":{"usage_idle":91.5},"name":"cpu","tags":{"cpu":"cpu0"},"timestamp":%d},`+
+		`{"fields":{"used":10},"name":"mem","tags":{},"timestamp":%d}]`, now.Unix(), now.Unix()) + "\n"
+	assert.Equal(t, expS, string(buf))
+
+	buf, err = s.SerializeBatch([]telegraf.Metric{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(buf))
+}
+
+func TestSerializeFlatten(t *testing.T) {
+	now := time.Unix(1458229140, 0)
+	m, err := metric.New("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5)}, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{
+		NameKey:      "measurement",
+		TimestampKey: "time",
+		Flatten:      true,
+		TagPrefix:    "tag_",
+		FieldPrefix:  "field_",
+	}
+	assert.NoError(t, s.Validate())
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+	expS := `{"field_usage_idle":91.5,"measurement":"cpu","tag_cpu":"cpu0","time":1458229140}` + "\n"
+	assert.Equal(t, expS, string(buf))
+}
+
+func TestSerializeRFC3339(t *testing.T) {
+	now := time.Date(2016, time.March, 17, 15, 39, 0, 500000000, time.FixedZone("", 3600))
+	m, err := metric.New("cpu", map[string]string{},
+		map[string]interface{}{"usage_idle": float64(91.5)}, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{TimestampFormat: "rfc3339"}
+	assert.NoError(t, s.Validate())
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+	expS := `{"fields":{"usage_idle":91.5},"name":"cpu","tags":{},"timestamp":"2016-03-17T14:39:00.5Z"}` + "\n"
+	assert.Equal(t, expS, string(buf))
+}
+
+func TestSerializePerField(t *testing.T) {
+	now := time.Unix(1458229140, 0)
+	m, err := metric.New("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5), "usage_user": int64(3)}, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{PerField: true}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+	expS := `{"field":"usage_idle","name":"cpu","tags":{"cpu":"cpu0"},"timestamp":1458229140,"value":91.5}` + "\n" +
+		`{"field":"usage_user","name":"cpu","tags":{"cpu":"cpu0"},"timestamp":1458229140,"value":3}` + "\n"
+	assert.Equal(t, expS, string(buf))
+
+	s = JsonSerializer{PerField: true, Flatten: true, TagPrefix: "tag_", FieldPrefix: "field_", Batch: true}
+	assert.NoError(t, s.Validate())
+	buf, err = s.SerializeBatch([]telegraf.Metric{m})
+	assert.NoError(t, err)
+	expS = `[{"field":"usage_idle","name":"cpu","tag_cpu":"cpu0","timestamp":1458229140,"value":91.5},` +
+		`{"field":"usage_user","name":"cpu","tag_cpu":"cpu0","timestamp":1458229140,"value":3}]` + "\n"
+	assert.Equal(t, expS, string(buf))
+}
+
+func TestValidateTimestampFormat(t *testing.T) {
+	s := JsonSerializer{TimestampFormat: "iso"}
+	assert.Error(t, s.Validate())
+}
+
+func TestValidateKeysCollision(t *testing.T) {
+	for _, s := range []JsonSerializer{
+		// flattened tags and fields could collide with each other or the
+		// name and timestamp
+		{Flatten: true},
+		{Flatten: true, TagPrefix: "tag_"},
+		{Flatten: true, TagPrefix: "x_", FieldPrefix: "x_"},
+		{Flatten: true, TagPrefix: "x", FieldPrefix: "x_"},
+		{Flatten: true, TagPrefix: "n", FieldPrefix: "f_"},
+		{Flatten: true, TagPrefix: "tag_", FieldPrefix: "field_", NameKey: "tag_name"},
+		{Flatten: true, TagPrefix: "tag_", FieldPrefix: "v", PerField: true},
+		// fixed keys used twice
+		{NameKey: "time", TimestampKey: "time"},
+		{NameKey: "field", PerField: true},
+		// the tags and fields objects when not flattened
+		{NameKey: "tags"},
+		{NameKey: "fields"},
+		{TimestampKey: "tags"},
+		{TimestampKey: "tags", PerField: true},
+	} {
+		assert.Error(t, s.Validate(), "%+v", s)
+	}
+
+	// there is no fields object with an object per field, and no tags or
+	// fields objects once flattened
+	for _, s := range []JsonSerializer{
+		{NameKey: "fields", PerField: true},
+		{NameKey: "tags", Flatten: true, TagPrefix: "tag_", FieldPrefix: "field_"},
+	} {
+		assert.NoError(t, s.Validate(), "%+v", s)
+	}
+}
+
+func TestSerializeBatchDisabled(t *testing.T) {
+	now := time.Now()
+	m1, err := metric.New("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5)}, now)
+	assert.NoError(t, err)
+	m2, err := metric.New("mem", map[string]string{},
+		map[string]interface{}{"used": int64(10)}, now)
+	assert.NoError(t, err)
+
+	// without batching, the batch is the serialized metrics, a line each
+	s := JsonSerializer{}
+	assert.False(t, s.Batching())
+	buf, err := s.SerializeBatch([]telegraf.Metric{m1, m2})
+	assert.NoError(t, err)
+	buf1, err := s.Serialize(m1)
+	assert.NoError(t, err)
+	buf2, err := s.Serialize(m2)
+	assert.NoError(t, err)
+	assert.Equal(t, string(buf1)+string(buf2), string(buf))
+}
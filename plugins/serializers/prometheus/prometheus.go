@@ -0,0 +1,276 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// PrometheusSerializer writes metrics in the Prometheus text exposition
+// format. Metric names follow the prometheus_client output: the fields of a
+// metric become samples named after the measurement and the field, tags and
+// string fields become labels.
+type PrometheusSerializer struct {
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// family holds the samples of a metric family, which are written after a
+// single TYPE line.
+type family struct {
+	name    string
+	typ     string
+	samples bytes.Buffer
+}
+
+// familySet holds the metric families in the order they are first seen.
+type familySet struct {
+	families []*family
+	index    map[string]*family
+}
+
+func newFamilySet() *familySet {
+	return &familySet{index: make(map[string]*family)}
+}
+
+// get returns the buffer of the samples of the family called name, the type
+// of a family is the one of its first metric.
+func (fs *familySet) get(name, typ string) *bytes.Buffer {
+	f, ok := fs.index[name]
+	if !ok {
+		f = &family{name: name, typ: typ}
+		fs.index[name] = f
+		fs.families = append(fs.families, f)
+	}
+	return &f.samples
+}
+
+func (fs *familySet) bytes() []byte {
+	var buf bytes.Buffer
+	for _, f := range fs.families {
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.typ)
+		buf.Write(f.samples.Bytes())
+	}
+	return buf.Bytes()
+}
+
+func (s *PrometheusSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	fs := newFamilySet()
+	addMetric(fs, metric)
+	return fs.bytes(), nil
+}
+
+// Batching returns true, as a batch has a single TYPE line per family.
+func (s *PrometheusSerializer) Batching() bool {
+	return true
+}
+
+// SerializeBatch writes metrics grouped by family, so that each family has a
+// single TYPE line.
+func (s *PrometheusSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	fs := newFamilySet()
+	for _, metric := range metrics {
+		addMetric(fs, metric)
+	}
+	return fs.bytes(), nil
+}
+
+func addMetric(fs *familySet, metric telegraf.Metric) {
+	labels := makeLabels(metric)
+	ts := metric.UnixNano() / 1000000
+
+	switch metric.Type() {
+	case telegraf.Summary:
+		addDistribution(fs, metric, labels, ts, "summary", "quantile", "")
+	case telegraf.Histogram:
+		addDistribution(fs, metric, labels, ts, "histogram", "le", "_bucket")
+	default:
+		typ := "untyped"
+		switch metric.Type() {
+		case telegraf.Counter:
+			typ = "counter"
+		case telegraf.Gauge:
+			typ = "gauge"
+		}
+
+		fields := metric.Fields()
+		for _, fn := range sortedKeys(fields) {
+			value, ok := toFloat(fields[fn])
+			if !ok {
+				continue
+			}
+			// the value field and the field named after the type are
+			// written under the measurement name, as the prometheus input
+			// names them.
+			name := sanitize(metric.Name() + "_" + fn)
+			if fn == "value" || fn == typ {
+				name = sanitize(metric.Name())
+			}
+			writeSample(fs.get(name, typ), name, labels, value, ts)
+		}
+	}
+}
+
+// addDistribution adds a summary or a histogram. The fields named after a
+// number are the quantiles or the bucket upper bounds, they are written in
+// increasing order with the number as the key label, followed by the sum and
+// count.
+func addDistribution(
+	fs *familySet,
+	metric telegraf.Metric,
+	labels []label,
+	ts int64,
+	typ string,
+	key string,
+	suffix string,
+) {
+	type point struct {
+		bound float64
+		value float64
+	}
+	var points []point
+	var sum, count float64
+	var hasSum, hasCount, hasInf bool
+
+	for fn, fv := range metric.Fields() {
+		value, ok := toFloat(fv)
+		if !ok {
+			continue
+		}
+		switch fn {
+		case "sum":
+			sum, hasSum = value, true
+		case "count":
+			count, hasCount = value, true
+		default:
+			bound, err := strconv.ParseFloat(fn, 64)
+			if err != nil {
+				continue
+			}
+			if math.IsInf(bound, 1) {
+				hasInf = true
+			}
+			points = append(points, point{bound: bound, value: value})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].bound < points[j].bound
+	})
+	// a histogram must have a +Inf bucket, which holds all the observations
+	if typ == "histogram" && !hasInf && hasCount {
+		points = append(points, point{bound: math.Inf(1), value: count})
+	}
+
+	name := sanitize(metric.Name())
+	buf := fs.get(name, typ)
+	for _, p := range points {
+		pointLabels := append(labels[:len(labels):len(labels)],
+			label{name: key, value: formatFloat(p.bound)})
+		writeSample(buf, name+suffix, pointLabels, p.value, ts)
+	}
+	if hasSum {
+		writeSample(buf, name+"_sum", labels, sum, ts)
+	}
+	if hasCount {
+		writeSample(buf, name+"_count", labels, count, ts)
+	}
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels []label, value float64, ts int64) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(l.name)
+			buf.WriteString(`="`)
+			buf.WriteString(labelValueEscaper.Replace(l.value))
+			buf.WriteByte('"')
+		}
+		buf.WriteByte('}')
+	}
+	fmt.Fprintf(buf, " %s %d\n", formatFloat(value), ts)
+}
+
+// makeLabels returns the tags and string fields of metric, sorted by name.
+func makeLabels(metric telegraf.Metric) []label {
+	values := make(map[string]string)
+	for k, v := range metric.Tags() {
+		values[sanitize(k)] = v
+	}
+	for fn, fv := range metric.Fields() {
+		if s, ok := fv.(string); ok {
+			values[sanitize(fn)] = s
+		}
+	}
+
+	labels := make([]label, 0, len(values))
+	for name, value := range values {
+		labels = append(labels, label{name: name, value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toFloat returns the value of a numeric field, string and bool fields are
+// not samples.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func sanitize(value string) string {
+	name := invalidNameCharRE.ReplaceAllString(value, "_")
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var now = time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+func serialize(t *testing.T, m telegraf.Metric) string {
+	s := &PrometheusSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestSerializeUntyped(t *testing.T) {
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "localhost", "cpu-id": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": float64(91.5),
+			"value":      int64(1),
+			"state":      "ok",
+			"active":     true,
+		},
+		now,
+	)
+	require.NoError(t, err)
+
+	expected := `# TYPE cpu_usage_idle untyped
+cpu_usage_idle{cpu_id="cpu0",host="localhost",state="ok"} 91.5 1289430000000
+# TYPE cpu untyped
+cpu{cpu_id="cpu0",host="localhost",state="ok"} 1 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeCounterAndGauge(t *testing.T) {
+	m, err := metric.New(
+		"http_requests",
+		map[string]string{"code": "200"},
+		map[string]interface{}{"counter": float64(1027)},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	expected := `# TYPE http_requests counter
+http_requests{code="200"} 1027 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+
+	m, err = metric.New(
+		"mem",
+		map[string]string{},
+		map[string]interface{}{"gauge": float64(3), "free": int64(1024)},
+		now,
+		telegraf.Gauge,
+	)
+	require.NoError(t, err)
+	expected = `# TYPE mem_free gauge
+mem_free 1024 1289430000000
+# TYPE mem gauge
+mem 3 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeSummary(t *testing.T) {
+	m, err := metric.New(
+		"rpc_duration_seconds",
+		map[string]string{"service": "a"},
+		map[string]interface{}{
+			"0.99":  float64(76656),
+			"0.5":   float64(4773),
+			"0.9":   float64(9001),
+			"sum":   float64(17560473),
+			"count": float64(2693),
+		},
+		now,
+		telegraf.Summary,
+	)
+	require.NoError(t, err)
+
+	expected := `# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{service="a",quantile="0.5"} 4773 1289430000000
+rpc_duration_seconds{service="a",quantile="0.9"} 9001 1289430000000
+rpc_duration_seconds{service="a",quantile="0.99"} 76656 1289430000000
+rpc_duration_seconds_sum{service="a"} 1.7560473e+07 1289430000000
+rpc_duration_seconds_count{service="a"} 2693 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeHistogram(t *testing.T) {
+	m, err := metric.New(
+		"request_duration",
+		map[string]string{},
+		map[string]interface{}{
+			"0.05":  float64(24054),
+			"0.1":   float64(33444),
+			"1":     float64(133988),
+			"+Inf":  float64(144320),
+			"sum":   float64(53423),
+			"count": float64(144320),
+		},
+		now,
+		telegraf.Histogram,
+	)
+	require.NoError(t, err)
+
+	expected := `# TYPE request_duration histogram
+request_duration_bucket{le="0.05"} 24054 1289430000000
+request_duration_bucket{le="0.1"} 33444 1289430000000
+request_duration_bucket{le="1"} 133988 1289430000000
+request_duration_bucket{le="+Inf"} 144320 1289430000000
+request_duration_sum 53423 1289430000000
+request_duration_count 144320 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeHistogramAddsInfBucket(t *testing.T) {
+	m, err := metric.New(
+		"request_duration",
+		map[string]string{},
+		map[string]interface{}{
+			"0.5":   float64(3),
+			"count": float64(5),
+		},
+		now,
+		telegraf.Histogram,
+	)
+	require.NoError(t, err)
+
+	expected := `# TYPE request_duration histogram
+request_duration_bucket{le="0.5"} 3 1289430000000
+request_duration_bucket{le="+Inf"} 5 1289430000000
+request_duration_count 5 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeEscapesLabels(t *testing.T) {
+	m, err := metric.New(
+		"2xx.responses",
+		map[string]string{"path": `C:\dir "quoted"`},
+		map[string]interface{}{"value": float64(1)},
+		now,
+	)
+	require.NoError(t, err)
+
+	expected := `# TYPE _2xx_responses untyped
+_2xx_responses{path="C:\\dir \"quoted\""} 1 1289430000000
+`
+	assert.Equal(t, expected, serialize(t, m))
+}
+
+func TestSerializeBatchGroupsFamilies(t *testing.T) {
+	m1, err := metric.New(
+		"http_requests",
+		map[string]string{"code": "200"},
+		map[string]interface{}{"counter": float64(1027)},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	m2, err := metric.New(
+		"mem",
+		map[string]string{},
+		map[string]interface{}{"free": int64(1024)},
+		now,
+		telegraf.Gauge,
+	)
+	require.NoError(t, err)
+	m3, err := metric.New(
+		"http_requests",
+		map[string]string{"code": "500"},
+		map[string]interface{}{"counter": float64(3)},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+
+	s := &PrometheusSerializer{}
+	buf, err := s.SerializeBatch([]telegraf.Metric{m1, m2, m3})
+	require.NoError(t, err)
+
+	expected := `# TYPE http_requests counter
+http_requests{code="200"} 1027 1289430000000
+http_requests{code="500"} 3 1289430000000
+# TYPE mem_free gauge
+mem_free 1024 1289430000000
+`
+	assert.Equal(t, expected, string(buf))
+}
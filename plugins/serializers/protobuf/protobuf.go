@@ -0,0 +1,16 @@
+package protobuf
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/protobuf"
+)
+
+// ProtobufSerializer writes metrics as length-delimited Metric messages,
+// see internal/protobuf/metric.proto. Unlike line protocol, the field types
+// and the type of the metric are kept.
+type ProtobufSerializer struct {
+}
+
+func (s *ProtobufSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return protobuf.MarshalDelimited(metric), nil
+}
@@ -0,0 +1,70 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{
+			"f": float64(1.5),
+			"i": int64(-2),
+			"b": true,
+			"s": "ok",
+		},
+		time.Unix(0, 1000),
+		telegraf.Gauge,
+	)
+	require.NoError(t, err)
+
+	s := ProtobufSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := []byte{
+		// message length
+		58,
+		// name
+		0x0a, 3, 'c', 'p', 'u',
+		// tags
+		0x12, 9, 0x0a, 4, 'h', 'o', 's', 't', 0x12, 1, 'a',
+		// fields, sorted by key
+		0x1a, 5, 0x0a, 1, 'b', 0x20, 1,
+		0x1a, 12, 0x0a, 1, 'f', 0x11, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f,
+		0x1a, 5, 0x0a, 1, 'i', 0x18, 3,
+		0x1a, 7, 0x0a, 1, 's', 0x2a, 2, 'o', 'k',
+		// time
+		0x20, 0xe8, 0x07,
+		// type
+		0x28, 2,
+	}
+	assert.Equal(t, expected, buf)
+}
+
+func TestSerializeUntypedMetric(t *testing.T) {
+	m, err := metric.New("m", map[string]string{},
+		map[string]interface{}{"v": int64(1)}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	s := ProtobufSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	// the type is left out for untyped metrics
+	expected := []byte{
+		12,
+		0x0a, 1, 'm',
+		0x1a, 5, 0x0a, 1, 'v', 0x18, 2,
+		0x20, 0,
+	}
+	assert.Equal(t, expected, buf)
+}
@@ -6,9 +6,12 @@ import (
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+	"github.com/influxdata/telegraf/plugins/serializers/protobuf"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -27,10 +30,49 @@ type Serializer interface {
 	Serialize(metric telegraf.Metric) ([]byte, error)
 }
 
+// BatchSerializer is implemented by the serializers which can write a batch
+// of metrics differently than the concatenation of the serialized metrics,
+// such as formats with a single envelope or header per batch.
+type BatchSerializer interface {
+	// SerializeBatch takes a batch of telegraf metrics and turns them into a
+	// single byte buffer.
+	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
+
+	// Batching returns whether SerializeBatch writes batches differently
+	// than the concatenation of the serialized metrics, in which case the
+	// outputs send each batch as a single message.
+	Batching() bool
+}
+
+// Batching returns whether s is a BatchSerializer with batching enabled.
+func Batching(s Serializer) bool {
+	bs, ok := s.(BatchSerializer)
+	return ok && bs.Batching()
+}
+
+// SerializeBatch serializes metrics with the SerializeBatch method of s when
+// it is a BatchSerializer, and concatenates the serialized metrics otherwise.
+func SerializeBatch(s Serializer, metrics []telegraf.Metric) ([]byte, error) {
+	if bs, ok := s.(BatchSerializer); ok {
+		return bs.SerializeBatch(metrics)
+	}
+
+	var out []byte
+	for _, metric := range metrics {
+		buf, err := s.Serialize(metric)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, buf...)
+	}
+	return out, nil
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
-	// Dataformat can be one of: influx, graphite, or json
+	// Dataformat can be one of: influx, graphite, json, prometheus, carbon2,
+	// or protobuf
 	DataFormat string
 
 	// Prefix to add to all measurements, only supports Graphite
@@ -40,8 +82,22 @@ type Config struct {
 	// only supports Graphite
 	Template string
 
+	// Write Graphite tagged series instead of using the Template
+	GraphiteTagSupport bool
+
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration
+
+	// JSON layout options, see the json.JsonSerializer fields of the same
+	// names
+	JSONTimestampFormat string
+	JSONNameKey         string
+	JSONTimestampKey    string
+	JSONFlatten         bool
+	JSONTagPrefix       string
+	JSONFieldPrefix     string
+	JSONPerField        bool
+	JSONBatch           bool
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -52,9 +108,16 @@ func NewSerializer(config *Config) (Serializer, error) {
 	case "influx":
 		serializer, err = NewInfluxSerializer()
 	case "graphite":
-		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template)
+		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template,
+			config.GraphiteTagSupport)
 	case "json":
-		serializer, err = NewJsonSerializer(config.TimestampUnits)
+		serializer, err = newJSONSerializer(config)
+	case "prometheus":
+		serializer, err = NewPrometheusSerializer()
+	case "carbon2":
+		serializer, err = NewCarbon2Serializer()
+	case "protobuf":
+		serializer, err = NewProtobufSerializer()
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -65,13 +128,44 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return &json.JsonSerializer{TimestampUnits: timestampUnits}, nil
 }
 
+func newJSONSerializer(config *Config) (Serializer, error) {
+	serializer := &json.JsonSerializer{
+		TimestampUnits:  config.TimestampUnits,
+		TimestampFormat: config.JSONTimestampFormat,
+		NameKey:         config.JSONNameKey,
+		TimestampKey:    config.JSONTimestampKey,
+		Flatten:         config.JSONFlatten,
+		TagPrefix:       config.JSONTagPrefix,
+		FieldPrefix:     config.JSONFieldPrefix,
+		PerField:        config.JSONPerField,
+		Batch:           config.JSONBatch,
+	}
+	if err := serializer.Validate(); err != nil {
+		return nil, err
+	}
+	return serializer, nil
+}
+
 func NewInfluxSerializer() (Serializer, error) {
 	return &influx.InfluxSerializer{}, nil
 }
 
-func NewGraphiteSerializer(prefix, template string) (Serializer, error) {
+func NewGraphiteSerializer(prefix, template string, tagSupport bool) (Serializer, error) {
 	return &graphite.GraphiteSerializer{
-		Prefix:   prefix,
-		Template: template,
+		Prefix:     prefix,
+		Template:   template,
+		TagSupport: tagSupport,
 	}, nil
 }
+
+func NewPrometheusSerializer() (Serializer, error) {
+	return &prometheus.PrometheusSerializer{}, nil
+}
+
+func NewCarbon2Serializer() (Serializer, error) {
+	return &carbon2.Carbon2Serializer{}, nil
+}
+
+func NewProtobufSerializer() (Serializer, error) {
+	return &protobuf.ProtobufSerializer{}, nil
+}
@@ -153,6 +153,46 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+// WithTracking returns a TrackingAccumulator that adds metrics to a and
+// reports every metric group as delivered as soon as it is added.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+type trackingAccumulator struct {
+	*Accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.AddMetrics(group)
+	id := telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+	a.delivered <- &deliveryInfo{id: id, delivered: true}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+var lastTrackingID uint64
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }